@@ -0,0 +1,86 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"time"
+)
+
+// AdmissionAction 准入控制的决定
+type AdmissionAction int
+
+const (
+	AdmissionAllow AdmissionAction = iota // 允许执行
+	AdmissionDelay                        // 等待 Delay 后再次询问
+	AdmissionSkip                         // 不执行，节点以 Skipped 结束，错误为 *AdmissionErr
+)
+
+// AdmissionDecision 准入控制对一个就绪节点的决定
+type AdmissionDecision struct {
+	Action AdmissionAction
+	Delay  time.Duration // 仅 AdmissionDelay 有效，小于或等于0时为 retryDeferInterval
+	Reason string        // 仅 AdmissionSkip 有效，记录在 AdmissionErr 中
+}
+
+// AdmissionController 准入控制，在每个就绪节点执行 processor 前（Condition、隔离等检查之后，获取并发名额之前）询问，
+// 可作为限流、预算、配额与自定义业务规则的统一扩展点。推迟期间节点不占用协程池的 worker，到期后重新提交到协程池，实现需保证并发安全
+type AdmissionController interface {
+	Admit(node IRuntimeNode) AdmissionDecision
+}
+
+// AdmissionFunc 函数形式的 AdmissionController
+type AdmissionFunc func(node IRuntimeNode) AdmissionDecision
+
+func (f AdmissionFunc) Admit(node IRuntimeNode) AdmissionDecision {
+	return f(node)
+}
+
+// admit 询问准入控制并执行节点，返回节点是否已进入终态。推迟执行时不占用当前协程，返回 false，到期后以新的任务调用 resumeAdmission
+func (node *runtimeNode[T]) admit(params T) bool {
+	decision := node.ctx.admission.Admit(node)
+	switch decision.Action {
+	case AdmissionSkip:
+		if node.finish(Skipped, &AdmissionErr{NodeName: node.name, Reason: decision.Reason}) {
+			node.callHooks(params)
+		}
+		return true
+	case AdmissionDelay:
+		delay := decision.Delay
+		if delay <= 0 {
+			delay = retryDeferInterval
+		}
+		// 在独立的协程内等待，到期或运行被终止时恢复，不占用协程池的 worker
+		go func() {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-node.ctx.ctx.Done():
+				timer.Stop()
+			}
+			f := func() { node.resumeAdmission(params) }
+			if !node.ctx.park(f) {
+				node.submit(f)
+			}
+		}()
+		return false
+	default:
+		return node.executeAdmitted(params)
+	}
+}
+
+// resumeAdmission 推迟到期后再次询问准入控制，节点结束后由当前协程通知子节点
+func (node *runtimeNode[T]) resumeAdmission(params T) {
+	var done bool
+	if cause := node.ctx.cause(); cause != nil {
+		node.cancel(params, cause)
+		done = true
+	} else {
+		done = node.admit(params)
+	}
+	if done {
+		node.follow(node.settle(params), params, true)
+	}
+}
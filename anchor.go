@@ -0,0 +1,17 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+// NewAnchorNode 创建锚点节点，锚点不执行任何逻辑，仅用于分组与汇合。
+// 锚点在上游节点通知依赖完成时于同一协程内同步结束，不提交到协程池、不创建协程与定时器，在超大图中几乎没有开销。
+// Processor 为 nil 且未设置 Canary、Condition、钩子函数（OnStart、OnRetry、OnSuccess、OnFailure、OnFinish）、
+// Load、Cache、Resources、MaxConcurrent 与 Exclusive 的节点同样被视为锚点
+func NewAnchorNode[T any](name string, deps ...*Node[T]) *Node[T] {
+	return &Node[T]{
+		Name:         name,
+		Dependencies: deps,
+	}
+}
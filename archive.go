@@ -0,0 +1,142 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// RetentionPolicy 运行归档的保留策略
+type RetentionPolicy struct {
+	// KeepLast 保留完整结果的最近运行数，更早的运行被压缩为按天汇总的统计，小于或等于0时表示不压缩
+	KeepLast int
+	// MaxDays 按天汇总的统计最多保留的天数（含当天），早于该天数的统计被清理，小于或等于0时表示不限制
+	MaxDays int
+	// Interval 后台清理的间隔，小于或等于0时在每次 Add 时同步压缩
+	Interval time.Duration
+}
+
+// DailyStats 被压缩的运行按天（UTC）汇总的统计
+type DailyStats struct {
+	Date      string        `json:"date"` // 格式为 2006-01-02
+	Runs      int           `json:"runs"`
+	Failed    int           `json:"failed"` // 运行被终止或存在失败节点的运行数
+	TotalCost time.Duration `json:"total_cost"`
+	MaxCost   time.Duration `json:"max_cost"`
+}
+
+// RunArchive 运行结果的内存归档，按保留策略压缩较早的运行，避免长期运行的服务无限增长
+type RunArchive struct {
+	policy RetentionPolicy
+	mu     sync.Mutex
+	runs   []*RunResult // 按加入顺序排列
+	daily  map[string]*DailyStats
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewRunArchive 创建运行归档，policy.Interval 大于0时启动后台清理协程，不再使用时需调用 Close
+func NewRunArchive(policy RetentionPolicy) *RunArchive {
+	a := &RunArchive{
+		policy: policy,
+		daily:  make(map[string]*DailyStats),
+		stop:   make(chan struct{}),
+	}
+	if policy.Interval > 0 {
+		go a.janitor()
+	}
+	return a
+}
+
+// Add 归档一次运行的结果
+func (a *RunArchive) Add(result *RunResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.runs = append(a.runs, result)
+	if a.policy.Interval <= 0 {
+		a.compact()
+	}
+}
+
+// Runs 获取保留完整结果的运行，按加入顺序排列
+func (a *RunArchive) Runs() []*RunResult {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.runs)
+}
+
+// Daily 获取被压缩的运行按天汇总的统计，按日期升序排列
+func (a *RunArchive) Daily() []DailyStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	stats := make([]DailyStats, 0, len(a.daily))
+	for _, date := range slices.Sorted(maps.Keys(a.daily)) {
+		stats = append(stats, *a.daily[date])
+	}
+	return stats
+}
+
+// Compact 立即按保留策略压缩
+func (a *RunArchive) Compact() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.compact()
+}
+
+// Close 停止后台清理协程，可重复调用
+func (a *RunArchive) Close() {
+	a.once.Do(func() {
+		close(a.stop)
+	})
+}
+
+func (a *RunArchive) janitor() {
+	ticker := time.NewTicker(a.policy.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.Compact()
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// compact 将超出 KeepLast 的运行汇总到按天统计中，并清理超出 MaxDays 的统计，需持有锁
+func (a *RunArchive) compact() {
+	if keep := a.policy.KeepLast; keep > 0 && len(a.runs) > keep {
+		n := len(a.runs) - keep
+		for _, result := range a.runs[:n] {
+			date := result.Begin.UTC().Format(time.DateOnly)
+			stats := a.daily[date]
+			if stats == nil {
+				stats = &DailyStats{Date: date}
+				a.daily[date] = stats
+			}
+			stats.Runs++
+			if result.Err() != nil {
+				stats.Failed++
+			}
+			stats.TotalCost += result.Cost
+			stats.MaxCost = max(stats.MaxCost, result.Cost)
+		}
+		// 复制到新的切片，释放被压缩的运行
+		a.runs = slices.Clone(a.runs[n:])
+	}
+	if a.policy.MaxDays > 0 {
+		// 按日期而不是统计的条数清理，没有运行的日期同样计入天数
+		cutoff := time.Now().UTC().AddDate(0, 0, -a.policy.MaxDays).Format(time.DateOnly)
+		for date := range a.daily {
+			if date <= cutoff {
+				delete(a.daily, date)
+			}
+		}
+	}
+}
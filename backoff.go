@@ -6,6 +6,7 @@
 package easydag
 
 import (
+	"math"
 	"math/rand"
 	"time"
 )
@@ -35,10 +36,11 @@ func ExponentBase2(a uint) uint {
 }
 
 // BackoffLinear is very simple: it waits for a fixed period of time between calls.
+//
+// Deprecated: the wait does not grow linearly, use ConstantBackoff instead.
+// For waits that grow with the attempt number use LinearBackoff.
 func BackoffLinear(waitBetween time.Duration) BackoffFunc {
-	return func(attempt uint) time.Duration {
-		return waitBetween
-	}
+	return ConstantBackoff(waitBetween)
 }
 
 // BackoffLinearWithJitter waits a set period of time, allowing for jitter (fractional adjustment).
@@ -67,3 +69,39 @@ func BackoffExponentialWithJitter(scalar time.Duration, jitterFraction float64)
 		return JitterUp(scalar*time.Duration(ExponentBase2(attempt)), jitterFraction)
 	}
 }
+
+// ConstantBackoff waits for the same duration d before every retry.
+func ConstantBackoff(d time.Duration) BackoffFunc {
+	return func(attempt uint) time.Duration {
+		return d
+	}
+}
+
+// LinearBackoff waits step multiplied by the attempt number, so the waits are step, 2*step, 3*step...
+func LinearBackoff(step time.Duration) BackoffFunc {
+	return func(attempt uint) time.Duration {
+		return step * time.Duration(attempt)
+	}
+}
+
+// ExponentialBackoff waits base*2^(attempt-1), never exceeding maxWait (maxWait <= 0 means no cap),
+// and then applies jitter as a fraction like JitterUp. The jittered result is capped as well.
+func ExponentialBackoff(base, maxWait time.Duration, jitter float64) BackoffFunc {
+	return func(attempt uint) time.Duration {
+		wait := base
+		for i := uint(1); i < attempt; i++ {
+			// stop doubling once maxWait is reached or the duration would overflow
+			if (maxWait > 0 && wait >= maxWait) || wait > math.MaxInt64/2 {
+				break
+			}
+			wait *= 2
+		}
+		if jitter > 0 {
+			wait = JitterUp(wait, jitter)
+		}
+		if maxWait > 0 && wait > maxWait {
+			wait = maxWait
+		}
+		return wait
+	}
+}
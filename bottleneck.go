@@ -0,0 +1,147 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BottleneckItem 单个节点对图运行耗时的贡献
+type BottleneckItem struct {
+	Name           string
+	Status         NodeStatus
+	Cost           time.Duration
+	QueueWait      time.Duration
+	Retries        uint    // 重试次数，即运行次数减一
+	OnCriticalPath bool    // 是否位于关键路径上
+	CriticalShare  float64 // 位于关键路径上时，节点耗时占图总耗时的比例
+}
+
+// BottleneckReport 瓶颈分析报告，Items 按对总耗时的贡献从大到小排列
+type BottleneckReport struct {
+	Total               time.Duration
+	PeakConcurrency     int
+	AvgConcurrency      float64
+	CriticalPath        []string // 根据实际耗时回溯出的关键路径
+	PlannedCriticalPath []string // 根据节点预估权重（Node.Weight）计算的关键路径
+	Items               []BottleneckItem
+}
+
+// AnalyzeBottlenecks 根据运行结果分析瓶颈：沿实际结束时间回溯出关键路径，并按关键路径占比、耗时、排队时间对节点排序。
+// result 为 nil 或不是该图的运行结果（节点数不一致）时返回 nil
+func (dag *DAG[T]) AnalyzeBottlenecks(result *RunResult) *BottleneckReport {
+	if result == nil || len(result.Results) != len(dag.metaNodes) {
+		return nil
+	}
+	report := &BottleneckReport{
+		Total:           result.Cost,
+		PeakConcurrency: result.PeakConcurrency,
+		AvgConcurrency:  result.AvgConcurrency(),
+	}
+	report.PlannedCriticalPath, _ = dag.CriticalPath()
+	parents := make([][]int, len(dag.metaNodes))
+	for i, node := range dag.metaNodes {
+		for _, childIdx := range node.children {
+			parents[childIdx] = append(parents[childIdx], i)
+		}
+		for _, weakChildIdx := range node.weakChildren {
+			parents[weakChildIdx] = append(parents[weakChildIdx], i)
+		}
+	}
+	finish := func(idx int) (time.Time, bool) {
+		r := result.Results[idx]
+		if r.Begin.IsZero() {
+			return time.Time{}, false
+		}
+		return r.Begin.Add(r.Cost), true
+	}
+	// 选取最晚结束的节点作为关键路径终点，再不断回溯最晚结束的父节点
+	last, lastFinish := -1, time.Time{}
+	for idx := range result.Results {
+		if end, ok := finish(idx); ok && end.After(lastFinish) {
+			last, lastFinish = idx, end
+		}
+	}
+	onPath := make([]bool, len(result.Results))
+	for cur := last; cur != -1; {
+		onPath[cur] = true
+		report.CriticalPath = append(report.CriticalPath, dag.metaNodes[cur].name)
+		next, nextFinish := -1, time.Time{}
+		for _, parent := range parents[cur] {
+			if end, ok := finish(parent); ok && end.After(nextFinish) {
+				next, nextFinish = parent, end
+			}
+		}
+		cur = next
+	}
+	slices.Reverse(report.CriticalPath)
+
+	for idx, r := range result.Results {
+		item := BottleneckItem{
+			Name:           dag.metaNodes[idx].name,
+			Status:         r.Status,
+			Cost:           r.Cost,
+			QueueWait:      r.QueueWait,
+			OnCriticalPath: onPath[idx],
+		}
+		if r.Attempts > 1 {
+			item.Retries = r.Attempts - 1
+		}
+		if item.OnCriticalPath && result.Cost > 0 {
+			item.CriticalShare = float64(r.Cost) / float64(result.Cost)
+		}
+		report.Items = append(report.Items, item)
+	}
+	slices.SortStableFunc(report.Items, func(a, b BottleneckItem) int {
+		if a.CriticalShare != b.CriticalShare {
+			return cmp.Compare(b.CriticalShare, a.CriticalShare)
+		}
+		return cmp.Compare(b.Cost+b.QueueWait, a.Cost+a.QueueWait)
+	})
+	return report
+}
+
+func (r *BottleneckReport) String() string {
+	var str strings.Builder
+	_ = r.Write(&str)
+	return str.String()
+}
+
+// Write 以文本表格的形式输出报告
+func (r *BottleneckReport) Write(writer io.StringWriter) error {
+	_, err := writer.WriteString(fmt.Sprintf("total: %v\ncritical path: %s\n", r.Total, strings.Join(r.CriticalPath, " -> ")))
+	if err != nil {
+		return err
+	}
+	_, err = writer.WriteString(fmt.Sprintf("concurrency: peak %d, avg %.2f\n", r.PeakConcurrency, r.AvgConcurrency))
+	if err != nil {
+		return err
+	}
+	_, err = writer.WriteString(fmt.Sprintf("planned critical path: %s\n", strings.Join(r.PlannedCriticalPath, " -> ")))
+	if err != nil {
+		return err
+	}
+	_, err = writer.WriteString(fmt.Sprintf("%-24s %8s %14s %14s %8s\n", "node", "critical", "cost", "queue wait", "retries"))
+	if err != nil {
+		return err
+	}
+	for _, item := range r.Items {
+		critical := "-"
+		if item.OnCriticalPath {
+			critical = fmt.Sprintf("%.1f%%", item.CriticalShare*100)
+		}
+		_, err = writer.WriteString(fmt.Sprintf("%-24s %8s %14v %14v %8d\n", item.Name, critical, item.Cost, item.QueueWait, item.Retries))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"strconv"
+	"time"
+)
+
+// BuildOptions 构建图时的配置，构建后不可修改，图可被并发运行
+type BuildOptions[T any] struct {
+	// Name 图的名称，用于区分不同图中的同名节点（如 Exclusive 节点的租约键），为空时使用图指纹（见 DAG.Fingerprint）
+	Name string
+	// Hooks 默认的运行级钩子函数，RunOptions.Hooks 不为 nil 时以其为准
+	Hooks *RunHooks
+	// SLA 图运行总耗时的目标，违约记录在 RunResult.SLABreaches 中，小于或等于0时表示不设目标，计入图指纹
+	SLA time.Duration
+	// DedupKey RunShared 合并并发运行所用的键函数，键相同的参数被视为等价，为 nil 时 RunShared 不合并
+	DedupKey func(params T) string
+	// Middlewares processor 中间件，先出现的位于外层，位于 DAG.Use 注册的中间件外层，每次执行（包括重试）都会经过所有中间件。
+	// 没有 processor 的节点与模拟运行（RunOptions.Mock）不经过中间件
+	Middlewares []Middleware[T]
+	// NameFunc 未设置 Name 的节点的命名函数，为 nil 时使用 NameNoname
+	NameFunc NameFunc
+	// NoProgressPolicy 所有根节点均失败时的处理策略，默认为 NoProgressContinue
+	NoProgressPolicy NoProgressPolicy
+}
+
+// NewDAGWithOptions 与 NewDAG 相同，可指定构建配置
+func NewDAGWithOptions[T any](opts BuildOptions[T], nodes ...*Node[T]) (*DAG[T], error) {
+	return newDagBuilder(opts, nodes).build()
+}
+
+// Name 获取图的名称，构建时未指定时为图指纹的十六进制表示
+func (dag *DAG[T]) Name() string {
+	if dag.name == "" {
+		return strconv.FormatUint(dag.fingerprint, 16)
+	}
+	return dag.name
+}
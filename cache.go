@@ -0,0 +1,117 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache 节点结果的记忆化缓存，值为节点的输出（见 IRuntimeNode.SetOutput）。实现需并发安全
+type Cache interface {
+	// Get 获取键对应的值，不存在或已过期时返回 false
+	Get(key string) (any, bool)
+	// Set 写入键对应的值
+	Set(key string, value any)
+}
+
+// MemoryCache 带过期时间的内存缓存，过期的条目在读取时惰性删除，也可通过 Purge 主动清理
+type MemoryCache struct {
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value  any
+	expire time.Time // 零值表示永不过期
+}
+
+// NewMemoryCache 创建内存缓存，ttl 小于或等于0时表示永不过期
+func NewMemoryCache(ttl time.Duration) *MemoryCache {
+	return &MemoryCache{ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+func (c *MemoryCache) Get(key string) (any, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if !entry.expire.IsZero() && time.Now().After(entry.expire) {
+		c.mu.Lock()
+		// 可能在加锁前已被重新写入
+		if cur, ok := c.entries[key]; ok && cur.expire == entry.expire {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *MemoryCache) Set(key string, value any) {
+	entry := cacheEntry{value: value}
+	if c.ttl > 0 {
+		entry.expire = time.Now().Add(c.ttl)
+	}
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+}
+
+// Len 获取缓存中的条目数，包括尚未清理的过期条目
+func (c *MemoryCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// Purge 清理已过期的条目
+func (c *MemoryCache) Purge() {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		if !entry.expire.IsZero() && now.After(entry.expire) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// memoKey 返回节点在本次运行中的缓存键，未开启记忆化时返回 false
+func (node *runtimeNode[T]) memoKey() (string, bool) {
+	if node.cache == nil || node.cacheKey == nil || node.ctx.mock != nil {
+		return "", false
+	}
+	return node.name + "/" + node.inputKey, true
+}
+
+// loadMemo 尝试从记忆化缓存中恢复节点的输出，返回是否命中
+func (node *runtimeNode[T]) loadMemo() bool {
+	key, ok := node.memoKey()
+	if !ok {
+		return false
+	}
+	output, hit := node.cache.Get(key)
+	if hit {
+		node.output = output
+		node.cacheHit = true
+	}
+	return hit
+}
+
+// storeMemo 将成功执行的节点输出写入记忆化缓存。没有输出的节点通常通过 params 传递结果，
+// 命中时跳过执行会丢失对 params 的写入，因此不写入缓存
+func (node *runtimeNode[T]) storeMemo() {
+	if node.output == nil {
+		return
+	}
+	if key, ok := node.memoKey(); ok {
+		node.cache.Set(key, node.output)
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Checkpoint 节点结束时保存的检查点，用于进程崩溃后通过 DAG.Resume 恢复运行
+type Checkpoint struct {
+	Node     string        `json:"node"`
+	Status   NodeStatus    `json:"status"`
+	Output   []byte        `json:"output,omitempty"` // 经 RunOptions.Codec 序列化的节点输出
+	Begin    time.Time     `json:"begin,omitempty"`
+	Cost     time.Duration `json:"cost,omitempty"`
+	Attempts uint          `json:"attempts,omitempty"`
+	InputKey string        `json:"input_key,omitempty"`
+}
+
+// CheckpointStore 检查点的存储，同一次运行的检查点可能被并发保存，实现需保证并发安全
+type CheckpointStore interface {
+	// Save 保存运行 runID 中一个节点的检查点，同一节点可能被保存多次，以最后一次为准
+	Save(runID string, cp *Checkpoint) error
+	// Load 按保存顺序加载运行 runID 的所有检查点，运行不存在时返回空切片
+	Load(runID string) ([]*Checkpoint, error)
+}
+
+// EncodedOutput 从检查点恢复、尚未反序列化的节点输出，InputOf 会按目标类型自动反序列化
+type EncodedOutput struct {
+	Data  []byte
+	Codec Codec
+}
+
+// Decode 将输出反序列化到 v 中，v 需为指针
+func (output *EncodedOutput) Decode(v any) error {
+	return output.Codec.Decode(output.Data, v)
+}
+
+// checkpointSink 在节点结束时保存检查点，并转发给用户设置的 ResultSink
+type checkpointSink struct {
+	store CheckpointStore
+	codec Codec
+	next  ResultSink
+	mu    sync.Mutex
+	err   error // 第一个保存失败的错误
+}
+
+func (sink *checkpointSink) OnResult(runID string, result *NodeResult) {
+	if err := sink.save(runID, result); err != nil {
+		sink.mu.Lock()
+		if sink.err == nil {
+			sink.err = err
+		}
+		sink.mu.Unlock()
+	}
+	if sink.next != nil {
+		sink.next.OnResult(runID, result)
+	}
+}
+
+func (sink *checkpointSink) save(runID string, result *NodeResult) error {
+	cp := &Checkpoint{
+		Node:     result.Name,
+		Status:   result.Status,
+		Begin:    result.Begin,
+		Cost:     result.Cost,
+		Attempts: result.Attempts,
+		InputKey: result.InputKey,
+	}
+	if encoded, ok := result.Output.(*EncodedOutput); ok {
+		cp.Output = encoded.Data
+	} else if result.Output != nil {
+		data, err := sink.codec.Encode(result.Output)
+		if err != nil {
+			return err
+		}
+		cp.Output = data
+	}
+	return sink.store.Save(runID, cp)
+}
+
+// error 返回第一个保存失败的错误
+func (sink *checkpointSink) error() error {
+	if sink == nil {
+		return nil
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	return sink.err
+}
+
+// Resume 恢复运行 runID：从 opts.Checkpoint 加载检查点，已成功且所有祖先节点都已成功的节点直接复用检查点中的结果，其余节点重新执行，
+// 新的检查点继续保存在同一个运行 ID 下。opts.RunID 会被覆盖为 runID
+func (dag *DAG[T]) Resume(runID string, params T, opts RunOptions) (*RunResult, error) {
+	if opts.Checkpoint == nil {
+		return nil, NoCheckpointStoreErr
+	}
+	checkpoints, err := opts.Checkpoint.Load(runID)
+	if err != nil {
+		return nil, err
+	}
+	codec := opts.Codec
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	prev := &RunResult{ID: runID, Results: make([]*NodeResult, len(dag.metaNodes))}
+	latest := make(map[string]*Checkpoint, len(checkpoints))
+	for _, cp := range checkpoints {
+		latest[cp.Node] = cp
+	}
+	for i, node := range dag.metaNodes {
+		r := &NodeResult{Name: node.name, Meta: node.meta, Tags: node.tags, Owner: node.owner, AlertChannel: node.alertChannel, Status: Waiting}
+		if cp, ok := latest[node.name]; ok {
+			r.Status = cp.Status
+			r.Begin = cp.Begin
+			r.Cost = cp.Cost
+			r.Attempts = cp.Attempts
+			r.InputKey = cp.InputKey
+			if cp.Output != nil {
+				r.Output = &EncodedOutput{Data: cp.Output, Codec: codec}
+			}
+		}
+		prev.Results[i] = r
+	}
+	opts.RunID = runID
+	return dag.RerunFailedWithOptions(prev, params, opts), nil
+}
+
+// MemoryCheckpointStore 内存中的检查点存储，仅能在进程内恢复，适用于测试或由外层重试的场景
+type MemoryCheckpointStore struct {
+	mu   sync.Mutex
+	runs map[string][]*Checkpoint
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{runs: make(map[string][]*Checkpoint)}
+}
+
+func (s *MemoryCheckpointStore) Save(runID string, cp *Checkpoint) error {
+	c := *cp
+	s.mu.Lock()
+	s.runs[runID] = append(s.runs[runID], &c)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryCheckpointStore) Load(runID string) ([]*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.runs[runID]), nil
+}
+
+// Delete 删除运行 runID 的所有检查点
+func (s *MemoryCheckpointStore) Delete(runID string) {
+	s.mu.Lock()
+	delete(s.runs, runID)
+	s.mu.Unlock()
+}
+
+// FileCheckpointStore 基于文件的检查点存储，每次运行对应目录下的一个 JSON Lines 文件，检查点以追加方式写入
+type FileCheckpointStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileCheckpointStore 创建基于文件的检查点存储，dir 不存在时自动创建
+func NewFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileCheckpointStore{dir: dir}, nil
+}
+
+// path 返回运行 runID 的检查点文件路径，子图的运行 ID 中含有 /，需要转义
+func (s *FileCheckpointStore) path(runID string) string {
+	return filepath.Join(s.dir, url.PathEscape(runID)+".jsonl")
+}
+
+func (s *FileCheckpointStore) Save(runID string, cp *Checkpoint) error {
+	line, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.OpenFile(s.path(runID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	// 落盘后才算保存成功，避免进程崩溃时丢失
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Load 加载运行 runID 的检查点，忽略无法解析的行（如崩溃时写了一半的最后一行）
+func (s *FileCheckpointStore) Load(runID string) ([]*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.Open(s.path(runID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var checkpoints []*Checkpoint
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		cp := &Checkpoint{}
+		if err := json.Unmarshal(scanner.Bytes(), cp); err != nil {
+			continue
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, scanner.Err()
+}
+
+// Delete 删除运行 runID 的检查点文件
+func (s *FileCheckpointStore) Delete(runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(s.path(runID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
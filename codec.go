@@ -0,0 +1,61 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec 参数与节点输出的序列化方式，可接入 protobuf、msgpack 等自定义格式
+type Codec interface {
+	// Encode 序列化 v
+	Encode(v any) ([]byte, error)
+	// Decode 将 data 反序列化到 v 中，v 需为指针
+	Decode(data []byte, v any) error
+}
+
+// JSONCodec 基于 encoding/json 的 Codec
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Decode(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec 基于 encoding/gob 的 Codec，接口类型的值需事先通过 gob.Register 注册
+type GobCodec struct{}
+
+func (GobCodec) Encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Decode(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// EncodeOutput 使用 codec 序列化节点在本次运行中的输出，节点没有输出时返回 nil
+func EncodeOutput(codec Codec, result *NodeResult) ([]byte, error) {
+	if result == nil || result.Output == nil {
+		return nil, nil
+	}
+	return codec.Encode(result.Output)
+}
+
+// DecodeOutput 使用 codec 将 EncodeOutput 的结果反序列化为类型 O
+func DecodeOutput[O any](codec Codec, data []byte) (O, error) {
+	var output O
+	err := codec.Decode(data, &output)
+	return output, err
+}
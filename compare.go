@@ -0,0 +1,121 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// NodeDiff 同一节点在两次运行中的差异
+type NodeDiff struct {
+	Name       string
+	StatusA    NodeStatus
+	StatusB    NodeStatus
+	CostA      time.Duration
+	CostB      time.Duration
+	CostDelta  time.Duration // CostB - CostA
+	NewFailure bool          // A 中未失败，B 中失败
+	Fixed      bool          // A 中失败，B 中未失败
+}
+
+// RunComparison 两次运行结果的对比
+type RunComparison struct {
+	CostDelta   time.Duration // 图总耗时的变化，B - A
+	Nodes       []NodeDiff    // 两次运行中都存在的节点，按 B 中的顺序排列
+	NewFailures []string      // 新增失败的节点
+	Fixed       []string      // 不再失败的节点
+	Added       []string      // 仅在 B 中存在的节点
+	Removed     []string      // 仅在 A 中存在的节点
+}
+
+// CompareRuns 对比同一个图的两次运行结果（如新旧版本），节点按名称匹配，同名节点按出现顺序依次匹配
+func CompareRuns(a, b *RunResult) *RunComparison {
+	c := &RunComparison{CostDelta: b.Cost - a.Cost}
+	// 名称 -> A 中尚未匹配的同名节点结果
+	pending := make(map[string][]*NodeResult, len(a.Results))
+	for _, r := range a.Results {
+		pending[r.Name] = append(pending[r.Name], r)
+	}
+	for _, rb := range b.Results {
+		candidates := pending[rb.Name]
+		if len(candidates) == 0 {
+			c.Added = append(c.Added, rb.Name)
+			continue
+		}
+		ra := candidates[0]
+		pending[rb.Name] = candidates[1:]
+		diff := NodeDiff{
+			Name:       rb.Name,
+			StatusA:    ra.Status,
+			StatusB:    rb.Status,
+			CostA:      ra.Cost,
+			CostB:      rb.Cost,
+			CostDelta:  rb.Cost - ra.Cost,
+			NewFailure: ra.Status != Failed && rb.Status == Failed,
+			Fixed:      ra.Status == Failed && rb.Status != Failed,
+		}
+		if diff.NewFailure {
+			c.NewFailures = append(c.NewFailures, rb.Name)
+		}
+		if diff.Fixed {
+			c.Fixed = append(c.Fixed, rb.Name)
+		}
+		c.Nodes = append(c.Nodes, diff)
+	}
+	for _, r := range a.Results {
+		if candidates := pending[r.Name]; len(candidates) > 0 {
+			c.Removed = append(c.Removed, r.Name)
+			pending[r.Name] = candidates[1:]
+		}
+	}
+	return c
+}
+
+func (c *RunComparison) String() string {
+	var str strings.Builder
+	_ = c.Write(&str)
+	return str.String()
+}
+
+// Write 以文本的形式输出对比结果，仅列出状态变化或耗时变化的节点
+func (c *RunComparison) Write(writer io.StringWriter) error {
+	_, err := writer.WriteString(fmt.Sprintf("total cost delta: %+v\n", c.CostDelta))
+	if err != nil {
+		return err
+	}
+	lists := []struct {
+		title string
+		names []string
+	}{
+		{"new failures", c.NewFailures},
+		{"fixed", c.Fixed},
+		{"added", c.Added},
+		{"removed", c.Removed},
+	}
+	for _, list := range lists {
+		if len(list.names) == 0 {
+			continue
+		}
+		_, err = writer.WriteString(fmt.Sprintf("%s: %s\n", list.title, strings.Join(list.names, ", ")))
+		if err != nil {
+			return err
+		}
+	}
+	for _, diff := range c.Nodes {
+		if diff.StatusA == diff.StatusB && diff.CostDelta == 0 {
+			continue
+		}
+		_, err = writer.WriteString(fmt.Sprintf("%-24s %10s -> %-10s %14v -> %-14v %+v\n", diff.Name,
+			diff.StatusA, diff.StatusB, diff.CostA, diff.CostB, diff.CostDelta))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
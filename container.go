@@ -0,0 +1,62 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"sync"
+)
+
+// Container 运行级的依赖注入容器，通过 RunOptions.Container 设置，processor 可通过 IRuntimeNode.Container 按类型或键获取共享的客户端（DB、HTTP 等）
+type Container struct {
+	mu     sync.RWMutex
+	values map[any]any
+}
+
+// typeKey 以类型作为容器的键，不同的类型参数对应不同的键，无需反射
+type typeKey[S any] struct{}
+
+func NewContainer() *Container {
+	return &Container{values: make(map[any]any)}
+}
+
+// Set 按键注册对象，key 需可比较
+func (c *Container) Set(key any, value any) {
+	c.mu.Lock()
+	c.values[key] = value
+	c.mu.Unlock()
+}
+
+// Get 按键获取对象，容器为 nil 时返回 false
+func (c *Container) Get(key any) (any, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// Provide 按类型注册对象，同一类型只保留最后一次注册的对象
+func Provide[S any](c *Container, value S) {
+	c.Set(typeKey[S]{}, value)
+}
+
+// Resolve 按类型获取运行容器中的对象
+func Resolve[S any](node IRuntimeNode) (S, bool) {
+	return ResolveKey[S](node, typeKey[S]{})
+}
+
+// ResolveKey 按键获取运行容器中的对象，并断言为类型 S
+func ResolveKey[S any](node IRuntimeNode, key any) (S, bool) {
+	value, ok := node.Container().Get(key)
+	if !ok {
+		var zero S
+		return zero, false
+	}
+	s, ok := value.(S)
+	return s, ok
+}
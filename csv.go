@@ -0,0 +1,56 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+var csvHeader = []string{"run_id", "node", "status", "begin", "cost_ms", "attempts", "error"}
+
+// WriteAsCSV 以 CSV 格式输出各节点的运行结果，每个节点一行
+func (result *RunResult) WriteAsCSV(writer io.Writer) error {
+	w := csv.NewWriter(writer)
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+	for r := range result.All() {
+		begin, errMsg := "", ""
+		if !r.Begin.IsZero() {
+			begin = r.Begin.Format(time.RFC3339Nano)
+		}
+		if r.Err != nil {
+			errMsg = r.Err.Error()
+		}
+		err := w.Write([]string{
+			result.ID,
+			r.Name,
+			r.Status.String(),
+			begin,
+			strconv.FormatFloat(float64(r.Cost)/float64(time.Millisecond), 'f', 3, 64),
+			strconv.FormatUint(uint64(r.Attempts), 10),
+			errMsg,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (result *RunResult) SaveAsCSV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return result.WriteAsCSV(file)
+}
@@ -6,51 +6,215 @@
 package easydag
 
 import (
+	"cmp"
+	"context"
 	"fmt"
 	"io"
+	"iter"
+	"maps"
 	"os"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type DAG[T any] struct {
+	name      string // 图的名称，见 BuildOptions.Name
 	metaNodes []*nodeMetadata[T]
 	rootNodes []int
+	nodes     []*Node[T]       // 用户节点，与 metaNodes 一一对应，仅用于校验构建后是否被修改
+	index     map[*Node[T]]int // 用户节点 -> 元数据下标
+	// noProgressPolicy 所有根节点均失败时的处理策略
+	noProgressPolicy NoProgressPolicy
+	// slaMaxDuration 图运行总耗时的目标，小于或等于0时表示不设目标
+	slaMaxDuration time.Duration
+	// dedupKey、flights RunShared 合并并发运行所用的键函数与进行中的运行
+	dedupKey func(params T) string
+	flights  flightGroup
+	// hooks 默认的运行级钩子函数，见 BuildOptions.Hooks
+	hooks *RunHooks
+	// middlewares 构建配置与 Use 注册的中间件，mwSealed 为 true 时已包裹到各节点的 processor 上，不可再注册
+	mwMu        sync.Mutex
+	middlewares []Middleware[T]
+	mwSealed    atomic.Bool
+	// fingerprint 构建时计算的图指纹，见 Fingerprint
+	fingerprint uint64
+	// dispatchPlans 各调度顺序（见 RunOptions.DispatchOrder）下的根节点与子节点顺序，构建时计算
+	dispatchPlans [dispatchOrderCnt]*dispatchPlan
+}
+
+// dispatchPlan 按某种调度顺序排好的根节点与各节点子节点的下标，多次运行共享，不可修改
+type dispatchPlan struct {
+	roots        []int
+	children     [][]int // 强依赖于该节点的子节点，与图内节点一一对应
+	weakChildren [][]int // 弱依赖于该节点的子节点，与图内节点一一对应
 }
 
 // NewDAG 根据节点定义生成图，会进行环形依赖检测。至少需要传入叶子节点，会通过 dfs 扫描所有节点。
 func NewDAG[T any](nodes ...*Node[T]) (*DAG[T], error) {
-	return newDagBuilder(nodes).build()
+	return NewDAGWithOptions(BuildOptions[T]{}, nodes...)
+}
+
+func (dag *DAG[T]) Run(params T) *RunResult {
+	return dag.RunWithOptions(params, RunOptions{})
+}
+
+// RunContext 在 ctx 下运行图，ctx 取消后不再调度新的节点，未执行的节点标记为 Cancelled，退避等待会被中断，正在执行的 processor 不受影响
+func (dag *DAG[T]) RunContext(ctx context.Context, params T) *RunResult {
+	return dag.RunWithOptions(params, RunOptions{Context: ctx})
+}
+
+func (dag *DAG[T]) RunWithPool(pool IPool, params T) *RunResult {
+	return dag.RunWithOptions(params, RunOptions{Pool: pool})
+}
+
+// RunWithOptions 按照给定的配置运行图
+func (dag *DAG[T]) RunWithOptions(params T, opts RunOptions) *RunResult {
+	run := dag.start(params, opts)
+	<-run.done()
+	return run.result()
+}
+
+// RunTargets 仅执行目标节点及其所有祖先，其余节点以 Skipped 结束，适合只需要大图中部分输出的场景。
+// 不属于该图的目标节点被忽略，未指定目标或目标均不属于该图时所有节点以 Skipped 结束
+func (dag *DAG[T]) RunTargets(params T, targets ...*Node[T]) *RunResult {
+	return dag.RunTargetsWithOptions(params, RunOptions{}, targets...)
+}
+
+// RunTargetsWithOptions 与 RunTargets 相同，可指定运行配置，与 RunOptions.Targets 同时指定时取并集
+func (dag *DAG[T]) RunTargetsWithOptions(params T, opts RunOptions, targets ...*Node[T]) *RunResult {
+	run := dag.newRun(opts)
+	idxs := make([]int, 0, len(targets))
+	for _, target := range targets {
+		if idx, ok := dag.index[target]; ok {
+			idxs = append(idxs, idx)
+		}
+	}
+	run.prune(idxs)
+	run.dispatch(params)
+	<-run.done()
+	return run.result()
 }
 
-func (dag *DAG[T]) Run(params T) []*NodeResult {
-	return dag.RunWithPool(nil, params)
+// AllNodes 按图内节点顺序遍历所有节点，包括通过依赖关系间接加入的节点
+func (dag *DAG[T]) AllNodes() iter.Seq[*Node[T]] {
+	return func(yield func(*Node[T]) bool) {
+		for _, node := range dag.nodes {
+			if !yield(node) {
+				return
+			}
+		}
+	}
 }
 
-func (dag *DAG[T]) RunWithPool(pool IPool, params T) []*NodeResult {
-	ctx := newDagCtx(pool)
-	runtimeNodes := make([]*runtimeNode[T], len(dag.metaNodes))
+// topoOrder 返回节点下标的拓扑序，父节点总在子节点之前
+func (dag *DAG[T]) topoOrder() []int {
+	order := make([]int, 0, len(dag.metaNodes))
+	depCnt := make([]int32, len(dag.metaNodes))
+	order = append(order, dag.rootNodes...)
+	for i := 0; i < len(order); i++ {
+		node := dag.metaNodes[order[i]]
+		for _, children := range [][]int{node.children, node.weakChildren} {
+			for _, childIdx := range children {
+				depCnt[childIdx]++
+				if depCnt[childIdx] == dag.metaNodes[childIdx].depCnt {
+					order = append(order, childIdx)
+				}
+			}
+		}
+	}
+	return order
+}
+
+// buildDispatchPlans 计算各调度顺序下的根节点与子节点顺序，排序稳定，相同优先级时保持图内顺序
+func (dag *DAG[T]) buildDispatchPlans() {
+	// 不等待弱依赖且没有强依赖的节点与根节点一同调度
+	roots := slices.Clone(dag.rootNodes)
 	for i, node := range dag.metaNodes {
-		runtimeNodes[i] = newRuntimeNode(node, ctx)
+		if node.earlyStart && node.depCnt > 0 && node.strongCnt == 0 {
+			roots = append(roots, i)
+		}
+	}
+	slices.Sort(roots)
+	def := &dispatchPlan{
+		roots:        roots,
+		children:     make([][]int, len(dag.metaNodes)),
+		weakChildren: make([][]int, len(dag.metaNodes)),
 	}
-	for _, node := range runtimeNodes {
-		node.children = make([]*runtimeNode[T], len(node.nodeMetadata.children))
-		for i, childIdx := range node.nodeMetadata.children {
-			node.children[i] = runtimeNodes[childIdx]
+	for i, node := range dag.metaNodes {
+		def.children[i] = node.children
+		def.weakChildren[i] = node.weakChildren
+	}
+	dag.dispatchPlans[DispatchDefault] = def
+	priorities := make([]float64, len(dag.metaNodes))
+	for i, node := range dag.metaNodes {
+		priorities[i] = float64(node.priority)
+	}
+	weights, _ := dag.downstreamWeights()
+	for order, keys := range map[DispatchOrder][]float64{DispatchByPriority: priorities, DispatchByWeight: weights} {
+		sorted := func(nodes []int) []int {
+			nodes = slices.Clone(nodes)
+			slices.SortStableFunc(nodes, func(a, b int) int {
+				return cmp.Compare(keys[b], keys[a])
+			})
+			return nodes
 		}
-		node.weakChildren = make([]*runtimeNode[T], len(node.nodeMetadata.weakChildren))
-		for i, weakChildIdx := range node.nodeMetadata.weakChildren {
-			node.weakChildren[i] = runtimeNodes[weakChildIdx]
+		plan := &dispatchPlan{
+			roots:        sorted(def.roots),
+			children:     make([][]int, len(dag.metaNodes)),
+			weakChildren: make([][]int, len(dag.metaNodes)),
 		}
+		for i := range dag.metaNodes {
+			plan.children[i] = sorted(def.children[i])
+			plan.weakChildren[i] = sorted(def.weakChildren[i])
+		}
+		dag.dispatchPlans[order] = plan
 	}
+}
+
+// downstreamWeights 计算每个节点到叶子节点的最大权重和（包含自身），以及该路径上的下一个节点
+func (dag *DAG[T]) downstreamWeights() ([]float64, []int) {
+	weights := make([]float64, len(dag.metaNodes))
+	next := make([]int, len(dag.metaNodes))
+	order := dag.topoOrder()
+	for i := len(order) - 1; i >= 0; i-- {
+		idx := order[i]
+		node := dag.metaNodes[idx]
+		next[idx] = -1
+		for _, children := range [][]int{node.children, node.weakChildren} {
+			for _, childIdx := range children {
+				if next[idx] == -1 || weights[childIdx] > weights[next[idx]] {
+					next[idx] = childIdx
+				}
+			}
+		}
+		weights[idx] = node.weight
+		if next[idx] != -1 {
+			weights[idx] += weights[next[idx]]
+		}
+	}
+	return weights, next
+}
+
+// CriticalPath 根据节点的预估权重计算关键路径，返回路径上的节点名称与路径总权重
+func (dag *DAG[T]) CriticalPath() ([]string, float64) {
+	weights, next := dag.downstreamWeights()
+	start := -1
 	for _, idx := range dag.rootNodes {
-		runtimeNodes[idx].start(params)
+		if start == -1 || weights[idx] > weights[start] {
+			start = idx
+		}
+	}
+	if start == -1 {
+		return nil, 0
 	}
-	ctx.wg.Wait()
-	results := make([]*NodeResult, len(runtimeNodes))
-	for i, node := range runtimeNodes {
-		results[i] = node.getResult()
+	var path []string
+	for cur := start; cur != -1; cur = next[cur] {
+		path = append(path, dag.metaNodes[cur].name)
 	}
-	return results
+	return path, weights[start]
 }
 
 func (dag *DAG[T]) ToMermaid() string {
@@ -70,9 +234,23 @@ func (dag *DAG[T]) WriteAsMermaid(writer io.StringWriter) error {
 			return err
 		}
 	}
+	// 元数据以悬浮提示的形式展示
+	for i, node := range dag.metaNodes {
+		if len(node.meta) == 0 {
+			continue
+		}
+		_, err = writer.WriteString(fmt.Sprintf("    click %d callback \"%s\"\n", i, mermaidTooltip(node.meta)))
+		if err != nil {
+			return err
+		}
+	}
 	for i, node := range dag.metaNodes {
 		for _, childIdx := range node.children {
-			_, err = writer.WriteString(fmt.Sprintf("    %d --> %d\n", i, childIdx))
+			arrow := "-->"
+			if dag.metaNodes[childIdx].depConds[i] != nil {
+				arrow = "-->|if|"
+			}
+			_, err = writer.WriteString(fmt.Sprintf("    %d %s %d\n", i, arrow, childIdx))
 			if err != nil {
 				return err
 			}
@@ -87,6 +265,16 @@ func (dag *DAG[T]) WriteAsMermaid(writer io.StringWriter) error {
 	return nil
 }
 
+// mermaidTooltip 将元数据按键排序拼接为悬浮提示文本
+func mermaidTooltip(meta map[string]any) string {
+	keys := slices.Sorted(maps.Keys(meta))
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + fmt.Sprint(meta[key])
+	}
+	return strings.ReplaceAll(strings.Join(pairs, ", "), "\"", "#quot;")
+}
+
 func (dag *DAG[T]) SaveAsMermaid(path string) error {
 	file, err := os.Create(path)
 	if err != nil {
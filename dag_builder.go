@@ -6,12 +6,11 @@
 package easydag
 
 import (
-	"errors"
 	"slices"
-	"strings"
 )
 
 type dagBuilder[T any] struct {
+	opts      BuildOptions[T]    // 构建配置
 	nodes     []*Node[T]         // 用户输入的节点
 	metaNodes []*nodeMetadata[T] // 所有节点的元数据
 	index     map[*Node[T]]int   // 用户节点 -> 元数据下标
@@ -19,8 +18,9 @@ type dagBuilder[T any] struct {
 	next      []int              // 环检测：DFS实时搜索路径
 }
 
-func newDagBuilder[T any](nodes []*Node[T]) *dagBuilder[T] {
+func newDagBuilder[T any](opts BuildOptions[T], nodes []*Node[T]) *dagBuilder[T] {
 	return &dagBuilder[T]{
+		opts:      opts,
 		nodes:     nodes,
 		index:     make(map[*Node[T]]int, len(nodes)),
 		metaNodes: make([]*nodeMetadata[T], 0, len(nodes)),
@@ -34,6 +34,9 @@ func (b *dagBuilder[T]) build() (*DAG[T], error) {
 		}
 		b.add(node)
 	}
+	if err := b.applyPolicies(); err != nil {
+		return nil, err
+	}
 	b.visited = make([]bool, len(b.metaNodes))
 	b.next = make([]int, len(b.metaNodes))
 	for idx := range b.next {
@@ -44,12 +47,40 @@ func (b *dagBuilder[T]) build() (*DAG[T], error) {
 			return nil, err
 		}
 	}
-	dag := &DAG[T]{metaNodes: b.metaNodes}
+	dag := &DAG[T]{
+		name:             b.opts.Name,
+		hooks:            b.opts.Hooks,
+		slaMaxDuration:   b.opts.SLA,
+		dedupKey:         b.opts.DedupKey,
+		middlewares:      slices.Clone(b.opts.Middlewares),
+		noProgressPolicy: b.opts.NoProgressPolicy,
+		metaNodes:        b.metaNodes,
+		nodes:            make([]*Node[T], len(b.metaNodes)),
+		index:            b.index,
+	}
+	for node, idx := range b.index {
+		dag.nodes[idx] = node
+	}
+	for idx, node := range dag.nodes {
+		b.metaNodes[idx].checksum = checksum(node, b.index)
+	}
+	dag.fingerprint = fingerprint(b.metaNodes, b.opts.SLA)
 	for idx, node := range b.metaNodes {
 		if node.depCnt == 0 {
 			dag.rootNodes = append(dag.rootNodes, idx)
 		}
 	}
+	order := dag.topoOrder()
+	for i := len(order) - 1; i >= 0; i-- {
+		node := b.metaNodes[order[i]]
+		node.chainLen = 1
+		for _, childIdx := range node.children {
+			if chainLen := b.metaNodes[childIdx].chainLen + 1; chainLen > node.chainLen {
+				node.chainLen = chainLen
+			}
+		}
+	}
+	dag.buildDispatchPlans()
 	return dag, nil
 }
 
@@ -60,6 +91,9 @@ func (b *dagBuilder[T]) add(node *Node[T]) int {
 	idx := len(b.metaNodes)
 	b.index[node] = idx
 	medaData := newNodeMetadata(node)
+	if medaData.name == "" {
+		medaData.name = b.defaultName(idx, node.Processor)
+	}
 	b.metaNodes = append(b.metaNodes, medaData)
 	for _, dep := range node.Dependencies {
 		if dep == nil {
@@ -67,7 +101,15 @@ func (b *dagBuilder[T]) add(node *Node[T]) int {
 		}
 		depIdx := b.add(dep)
 		b.metaNodes[depIdx].children = append(b.metaNodes[depIdx].children, idx)
+		medaData.deps = append(medaData.deps, depIdx)
 		medaData.depCnt++
+		medaData.strongCnt++
+		if cond := node.DependencyConditions[dep]; cond != nil {
+			if medaData.depConds == nil {
+				medaData.depConds = make(map[int]func(params T) bool)
+			}
+			medaData.depConds[depIdx] = cond
+		}
 	}
 	for _, weakDep := range node.WeakDependencies {
 		if weakDep == nil {
@@ -75,6 +117,7 @@ func (b *dagBuilder[T]) add(node *Node[T]) int {
 		}
 		weakDepIdx := b.add(weakDep)
 		b.metaNodes[weakDepIdx].weakChildren = append(b.metaNodes[weakDepIdx].weakChildren, idx)
+		medaData.deps = append(medaData.deps, weakDepIdx)
 		medaData.depCnt++
 	}
 	return idx
@@ -89,7 +132,7 @@ func (b *dagBuilder[T]) detectCycle(idx int) error {
 		}
 		cycle = append(cycle, b.metaNodes[idx].name)
 		slices.Reverse(cycle)
-		return errors.New("cyclic dependency detected: " + strings.Join(cycle, " -> "))
+		return &CycleErr{Path: cycle}
 	}
 	if b.visited[idx] {
 		return nil
@@ -110,3 +153,18 @@ func (b *dagBuilder[T]) detectCycle(idx int) error {
 	b.next[idx] = -1
 	return nil
 }
+
+// applyPolicies 展开节点引用的策略预设
+func (b *dagBuilder[T]) applyPolicies() error {
+	for _, node := range b.metaNodes {
+		if node.policy == "" {
+			continue
+		}
+		policy, ok := LookupPolicy(node.policy)
+		if !ok {
+			return &UnknownPolicyErr{NodeName: node.name, Policy: node.policy}
+		}
+		node.applyPolicy(policy)
+	}
+	return nil
+}
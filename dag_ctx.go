@@ -6,19 +6,199 @@
 package easydag
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type dagCtx struct {
-	wg    sync.WaitGroup
-	pool  IPool
-	begin time.Time
+	// running 未结束的任务数，归零时关闭 done。调度根节点期间额外持有一个计数，避免根节点提前结束导致计数提前归零
+	running     atomic.Int64
+	done        chan struct{}
+	runID       string
+	parentRunID string
+	pool        IPool
+	class       RunClass
+	limiter     *Limiter
+	runLimiter  *Limiter // 本次运行独占的并发限制器
+	resources   *ResourceLimiter
+	admission   AdmissionController
+	// panicScope panic 影响的范围，panicHandler 发生 panic 时的回调
+	panicScope   PanicScope
+	panicHandler PanicHandler
+	leases       LeaseStore // 为 nil 时表示 Exclusive 不生效
+	leaseTTL     time.Duration
+	leaseHolder  string
+	container    *Container
+	bus          *DataBus
+	resultSink   ResultSink
+	checkpoint   *checkpointSink // 为 nil 时表示不保存检查点
+	sanitizer    ErrSanitizer
+	eventSink    EventSink
+	hooks        *RunHooks
+	mock         *MockOptions // 为 nil 时表示不模拟
+	sampled      bool         // 是否采集详细观测数据
+	canary       bool         // 是否执行金丝雀实现
+	labels       map[string]string
+	gauge        concurrencyGauge
+	retrying     peakGauge  // 进行中的重试数
+	poolStats    *PoolStats // 本次运行提交到协程池的任务统计，为 nil 时表示不统计
+	breaker      *RetryBreaker
+	noProgress   *noProgressTracker // 为 nil 时表示不检测
+	failFast     bool
+	begin        time.Time
+	end          time.Time
+	ctx          context.Context
+	cancel       context.CancelCauseFunc
+	stop         context.CancelFunc // 释放运行整体超时的定时器
+	onFinish     func()             // 运行结束、关闭 done 之前调用，用于汇总结果
+	// paused 暂停期间就绪的节点暂存在 parked 中，恢复或运行被终止时再调度
+	paused atomic.Bool
+	parkMu sync.Mutex
+	parked []func()
 }
 
-func newDagCtx(pool IPool) *dagCtx {
-	return &dagCtx{
-		begin: time.Now(),
-		pool:  pool,
+func newDagCtx(parent context.Context, pool IPool, timeout time.Duration) *dagCtx {
+	if parent == nil {
+		parent = context.Background()
 	}
+	var stop context.CancelFunc
+	if timeout > 0 {
+		parent, stop = context.WithTimeoutCause(parent, timeout, TimeoutErr)
+	}
+	ctx, cancel := context.WithCancelCause(parent)
+	dctx := &dagCtx{
+		done:   make(chan struct{}),
+		bus:    newDataBus(),
+		begin:  time.Now(),
+		pool:   pool,
+		ctx:    ctx,
+		cancel: cancel,
+		stop:   stop,
+	}
+	// 运行被终止时调度暂存的节点，使其尽快以取消结束，避免运行无法结束
+	context.AfterFunc(ctx, dctx.resume)
+	return dctx
+}
+
+// pause 暂停调度新的就绪节点，正在执行的节点不受影响
+func (ctx *dagCtx) pause() {
+	ctx.parkMu.Lock()
+	if ctx.cause() == nil {
+		ctx.paused.Store(true)
+	}
+	ctx.parkMu.Unlock()
+}
+
+// resume 恢复调度，并调度暂停期间就绪的节点
+func (ctx *dagCtx) resume() {
+	ctx.parkMu.Lock()
+	ctx.paused.Store(false)
+	parked := ctx.parked
+	ctx.parked = nil
+	ctx.parkMu.Unlock()
+	for _, f := range parked {
+		ctx.submit(f)
+	}
+}
+
+// park 运行暂停时暂存就绪节点的执行函数并返回 true，否则返回 false
+func (ctx *dagCtx) park(f func()) bool {
+	if !ctx.paused.Load() {
+		return false
+	}
+	ctx.parkMu.Lock()
+	defer ctx.parkMu.Unlock()
+	if !ctx.paused.Load() {
+		return false
+	}
+	ctx.parked = append(ctx.parked, f)
+	return true
+}
+
+// submit 提交任务到协程池，未设置协程池时使用独立的协程运行
+func (ctx *dagCtx) submit(f func()) {
+	if ctx.pool == nil {
+		go f()
+		return
+	}
+	f = ctx.observeTask(f)
+	if classPool, ok := ctx.pool.(IClassPool); ok {
+		classPool.SubmitWithClass(f, ctx.class)
+	} else {
+		ctx.pool.Submit(f)
+	}
+}
+
+// submitPinned 提交不可被工作窃取的任务，协程池不支持时与 submit 相同
+func (ctx *dagCtx) submitPinned(f func()) {
+	if pinnedPool, ok := ctx.pool.(IPinnedPool); ok {
+		pinnedPool.SubmitPinned(ctx.observeTask(f), ctx.class)
+		return
+	}
+	ctx.submit(f)
+}
+
+// idle 协程池是否没有排队中的任务，无法查询排队任务数的协程池视为非空闲
+func (ctx *dagCtx) idle() bool {
+	if ctx.pool == nil {
+		return true
+	}
+	pool, ok := ctx.pool.(IQueuedPool)
+	return ok && pool.QueueLen() == 0
+}
+
+func (ctx *dagCtx) add() {
+	ctx.running.Add(1)
+}
+
+func (ctx *dagCtx) release() {
+	if ctx.running.Add(-1) == 0 {
+		ctx.end = time.Now()
+		// 汇总结果会释放 context，需先取出终止原因
+		cause := ctx.cause()
+		if ctx.onFinish != nil {
+			ctx.onFinish()
+		}
+		if event := ctx.newEvent(EventRunFinished); event != nil {
+			event.Time = ctx.end
+			event.Cost = ctx.end.Sub(ctx.begin)
+			if cause != nil {
+				event.Error = ctx.sanitize(cause).Error()
+			}
+			ctx.eventSink.OnEvent(event)
+		}
+		close(ctx.done)
+	}
+}
+
+// sanitize 对写入 NodeResult、RunResult.Cause 与事件的错误脱敏。FailFastErr 与 NoProgressErr 携带的节点错误在创建时已脱敏，
+// 保留原类型以便 errors.As 匹配
+func (ctx *dagCtx) sanitize(err error) error {
+	if err == nil || ctx.sanitizer == nil {
+		return err
+	}
+	switch err.(type) {
+	case *FailFastErr, *NoProgressErr:
+		return err
+	}
+	return ctx.sanitizer(err)
+}
+
+// abort 终止本次运行，cause 为终止原因，可通过 context.Cause 获取
+func (ctx *dagCtx) abort(cause error) {
+	ctx.cancel(cause)
+	// 先取消自身再释放定时器，保证 cause 不被覆盖
+	if ctx.stop != nil {
+		ctx.stop()
+	}
+}
+
+// cause 返回本次运行被终止的原因，未终止时返回 nil
+func (ctx *dagCtx) cause() error {
+	if ctx.ctx.Err() == nil {
+		return nil
+	}
+	return context.Cause(ctx.ctx)
 }
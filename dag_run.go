@@ -0,0 +1,353 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"slices"
+	"sync/atomic"
+)
+
+// dagRun 图的一次运行
+type dagRun[T any] struct {
+	dag    *DAG[T]
+	opts   RunOptions
+	params T // 本次运行的参数，供运行外部终止节点时调用钩子函数
+	ctx    *dagCtx
+	nodes  []*runtimeNode[T] // 非懒加载模式下的运行时节点，与图内节点一一对应
+	// lazyNodes 懒加载模式下的运行时节点，节点在首次被通知依赖完成（或作为根节点被调度）时才创建
+	lazyNodes []atomic.Pointer[runtimeNode[T]]
+	plan      *dispatchPlan // 本次运行的调度顺序，见 RunOptions.DispatchOrder
+	reused    []*NodeResult // 复用的历史运行结果，与图内节点一一对应，为 nil 时表示不复用
+	disabled  []bool        // 本次运行禁用的节点，与图内节点一一对应，为 nil 时表示没有禁用的节点
+	pruned    []bool        // 不是目标节点祖先的节点，与图内节点一一对应，为 nil 时表示执行整个图
+	excluded  []bool        // 被标签过滤的节点，与图内节点一一对应，为 nil 时表示没有被过滤的节点
+	res       *RunResult    // 运行结束时汇总的结果
+}
+
+// start 创建运行时节点并调度根节点，不等待运行结束
+func (dag *DAG[T]) start(params T, opts RunOptions) *dagRun[T] {
+	run := dag.newRun(opts)
+	run.dispatch(params)
+	return run
+}
+
+// newRun 创建一次运行，调度前可设置需要复用的历史结果等
+func (dag *DAG[T]) newRun(opts RunOptions) *dagRun[T] {
+	dag.sealMiddlewares()
+	ctx := newDagCtx(opts.Context, opts.Pool, opts.Timeout)
+	ctx.limiter = opts.Limiter
+	if opts.MaxConcurrency > 0 {
+		ctx.runLimiter = NewLimiter(opts.MaxConcurrency)
+	}
+	ctx.resources = opts.Resources
+	ctx.admission = opts.Admission
+	ctx.panicHandler = opts.PanicHandler
+	ctx.panicScope = opts.PanicScope
+	ctx.class = opts.Class
+	ctx.container = opts.Container
+	ctx.breaker = opts.RetryBreaker
+	ctx.resultSink = opts.ResultSink
+	if opts.Checkpoint != nil {
+		codec := opts.Codec
+		if codec == nil {
+			codec = JSONCodec{}
+		}
+		ctx.checkpoint = &checkpointSink{store: opts.Checkpoint, codec: codec, next: opts.ResultSink}
+		ctx.resultSink = ctx.checkpoint
+	}
+	ctx.sanitizer = opts.ErrSanitizer
+	ctx.eventSink = opts.EventSink
+	ctx.hooks = opts.Hooks
+	if ctx.hooks == nil {
+		ctx.hooks = dag.hooks
+	}
+	ctx.mock = opts.Mock
+	ctx.labels = opts.Labels
+	ctx.failFast = opts.FailFast
+	if dag.noProgressPolicy == NoProgressFail {
+		ctx.noProgress = newNoProgressTracker(len(dag.rootNodes))
+	}
+	ctx.runID = opts.RunID
+	if ctx.runID == "" {
+		ctx.runID = newRunID()
+	}
+	parent, isSubRun := opts.Parent.(subRunParent)
+	if isSubRun {
+		ctx.parentRunID = parent.runID()
+		ctx.runID = ctx.parentRunID + "/" + ctx.runID
+	}
+	if opts.Leases != nil {
+		ctx.leases, ctx.leaseTTL, ctx.leaseHolder = opts.Leases, opts.LeaseTTL, opts.LeaseHolder
+		if ctx.leaseTTL <= 0 {
+			ctx.leaseTTL = defaultLeaseTTL
+		}
+		if ctx.leaseHolder == "" {
+			ctx.leaseHolder = ctx.runID
+		} else {
+			ctx.leaseHolder += "/" + ctx.runID
+		}
+	}
+	ctx.sampled = sampled(ctx.runID, opts.SampleRate)
+	ctx.gauge.noSamples = !ctx.sampled
+	ctx.canary = opts.CanaryRate >= 1 || (opts.CanaryRate > 0 && sampled("canary/"+ctx.runID, opts.CanaryRate))
+	if isSubRun {
+		ctx.inherit(parent.runCtx(), opts)
+	}
+	if opts.Pool != nil && ctx.sampled {
+		ctx.poolStats = &PoolStats{}
+	}
+	run := &dagRun[T]{
+		dag:  dag,
+		opts: opts,
+		ctx:  ctx,
+		plan: dag.dispatchPlans[DispatchDefault],
+	}
+	if opts.DispatchOrder > DispatchDefault && opts.DispatchOrder < dispatchOrderCnt {
+		run.plan = dag.dispatchPlans[opts.DispatchOrder]
+	}
+	ctx.onFinish = run.finish
+	if len(opts.Disabled) > 0 {
+		disabled := make(map[string]bool, len(opts.Disabled))
+		for _, name := range opts.Disabled {
+			disabled[name] = true
+		}
+		run.disabled = make([]bool, len(dag.metaNodes))
+		for i, node := range dag.metaNodes {
+			run.disabled[i] = disabled[node.name]
+		}
+	}
+	if len(opts.IncludeTags) > 0 || len(opts.ExcludeTags) > 0 {
+		run.excluded = make([]bool, len(dag.metaNodes))
+		for i, node := range dag.metaNodes {
+			run.excluded[i] = node.excludedBy(opts.IncludeTags, opts.ExcludeTags)
+		}
+	}
+	if len(opts.Targets) > 0 {
+		targets := make(map[string]bool, len(opts.Targets))
+		for _, name := range opts.Targets {
+			targets[name] = true
+		}
+		var idxs []int
+		for i, node := range dag.metaNodes {
+			if targets[node.name] {
+				idxs = append(idxs, i)
+			}
+		}
+		run.prune(idxs)
+	}
+	return run
+}
+
+// prune 标记不需要为目标节点执行的节点，targets 为空（如目标均不属于该图）时裁剪所有节点
+func (run *dagRun[T]) prune(targets []int) {
+	needed := make([]bool, len(run.dag.metaNodes))
+	stack := slices.Clone(targets)
+	for len(stack) > 0 {
+		idx := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if needed[idx] {
+			continue
+		}
+		needed[idx] = true
+		stack = append(stack, run.dag.metaNodes[idx].deps...)
+	}
+	if run.pruned == nil {
+		run.pruned = make([]bool, len(needed))
+		for i := range run.pruned {
+			run.pruned[i] = true
+		}
+	}
+	// 与 RunOptions.Targets 同时指定时取并集
+	for i, ok := range needed {
+		if ok {
+			run.pruned[i] = false
+		}
+	}
+}
+
+// isPruned 下标为 idx 的节点是否被裁剪或被标签过滤
+func (run *dagRun[T]) isPruned(idx int) bool {
+	return (run.pruned != nil && run.pruned[idx]) || (run.excluded != nil && run.excluded[idx])
+}
+
+// newNode 创建下标为 idx 的运行时节点
+func (run *dagRun[T]) newNode(idx int) *runtimeNode[T] {
+	node := newRuntimeNode(idx, run.dag.metaNodes[idx], run)
+	if run.reused != nil {
+		node.reused = run.reused[idx]
+		if node.reused != nil {
+			node.output = node.reused.Output
+		}
+	}
+	return node
+}
+
+// materialize 非懒加载模式下创建所有运行时节点，并将子节点下标转换为指针
+func (run *dagRun[T]) materialize() {
+	run.nodes = make([]*runtimeNode[T], len(run.dag.metaNodes))
+	for i := range run.dag.metaNodes {
+		run.nodes[i] = run.newNode(i)
+	}
+	for _, node := range run.nodes {
+		node.children = make([]*runtimeNode[T], len(run.plan.children[node.idx]))
+		for i, childIdx := range run.plan.children[node.idx] {
+			node.children[i] = run.nodes[childIdx]
+		}
+		node.weakChildren = make([]*runtimeNode[T], len(run.plan.weakChildren[node.idx]))
+		for i, weakChildIdx := range run.plan.weakChildren[node.idx] {
+			node.weakChildren[i] = run.nodes[weakChildIdx]
+		}
+	}
+}
+
+// node 获取下标为 idx 的运行时节点，懒加载模式下不存在时并发安全地创建
+func (run *dagRun[T]) node(idx int) *runtimeNode[T] {
+	if run.lazyNodes == nil {
+		return run.nodes[idx]
+	}
+	if node := run.lazyNodes[idx].Load(); node != nil {
+		return node
+	}
+	node := run.newNode(idx)
+	if run.lazyNodes[idx].CompareAndSwap(nil, node) {
+		return node
+	}
+	return run.lazyNodes[idx].Load()
+}
+
+// peek 获取下标为 idx 的运行时节点，懒加载模式下未创建时返回 nil
+func (run *dagRun[T]) peek(idx int) *runtimeNode[T] {
+	if run.lazyNodes == nil {
+		return run.nodes[idx]
+	}
+	return run.lazyNodes[idx].Load()
+}
+
+// dispatch 创建运行时节点并调度根节点
+func (run *dagRun[T]) dispatch(params T) {
+	run.prepare(params)
+	run.startRoots(params)
+}
+
+// prepare 创建运行时节点，调度根节点前调用，调用后即可终止节点或汇总结果
+func (run *dagRun[T]) prepare(params T) {
+	run.params = params
+	if run.opts.Lazy {
+		run.lazyNodes = make([]atomic.Pointer[runtimeNode[T]], len(run.dag.metaNodes))
+	} else {
+		run.materialize()
+	}
+	if event := run.ctx.newEvent(EventRunStarted); event != nil {
+		event.Time = run.ctx.begin
+		run.ctx.eventSink.OnEvent(event)
+	}
+	if run.ctx.hooks != nil && run.ctx.hooks.OnRunStart != nil {
+		run.ctx.hooks.OnRunStart(run.ctx.runID)
+	}
+	// 调度根节点期间持有一个计数，由 startRoots 释放
+	run.ctx.add()
+}
+
+// startRoots 调度根节点，除被裁剪的根节点外均提交到协程池，不在当前协程内执行 processor
+func (run *dagRun[T]) startRoots(params T) {
+	defer run.ctx.release()
+	for _, idx := range run.plan.roots {
+		node := run.node(idx)
+		if !node.markReady() {
+			continue
+		}
+		if run.isPruned(idx) {
+			node.schedule(params)
+		} else {
+			node.enqueue(params)
+		}
+	}
+}
+
+// kill 终止名为 name 的运行中节点，返回是否有节点被终止
+func (run *dagRun[T]) kill(name string) bool {
+	killed := false
+	for idx, meta := range run.dag.metaNodes {
+		if meta.name != name {
+			continue
+		}
+		if node := run.peek(idx); node != nil && node.kill(run.params) {
+			killed = true
+		}
+	}
+	return killed
+}
+
+// done 返回运行结束时关闭的 channel，可用于 select
+func (run *dagRun[T]) done() <-chan struct{} {
+	return run.ctx.done
+}
+
+// result 返回运行结果，需在运行结束后调用
+func (run *dagRun[T]) result() *RunResult {
+	return run.res
+}
+
+// finish 在运行结束时汇总结果并回调 OnRunFinish，由 dagCtx.release 调用一次
+func (run *dagRun[T]) finish() {
+	run.res = run.summarize()
+	run.ctx.emitSLABreaches(run.res.SLABreaches)
+	if run.ctx.hooks != nil && run.ctx.hooks.OnRunFinish != nil {
+		run.ctx.hooks.OnRunFinish(run.res)
+	}
+}
+
+// summarize 汇总运行结果
+func (run *dagRun[T]) summarize() *RunResult {
+	cause := run.ctx.cause()
+	// 释放 context 相关资源
+	run.ctx.abort(nil)
+	results := make([]*NodeResult, len(run.dag.metaNodes))
+	degraded := 0
+	for i, meta := range run.dag.metaNodes {
+		if node := run.peek(i); node != nil {
+			results[i] = node.getResult()
+		} else {
+			results[i] = &NodeResult{Name: meta.name, Meta: meta.meta, Tags: meta.tags, Owner: meta.owner, AlertChannel: meta.alertChannel, Status: Waiting}
+		}
+		// 运行被取消时，未被调度的节点视为已取消
+		if cause != nil && results[i].Status == Waiting {
+			results[i].Status = Cancelled
+			results[i].Err = run.ctx.sanitize(cause)
+		}
+		if results[i].Degraded && (results[i].Status == Succeeded || results[i].Status == Failed) {
+			degraded++
+		}
+	}
+	result := &RunResult{
+		ID:            run.ctx.runID,
+		ParentID:      run.ctx.parentRunID,
+		Fingerprint:   run.dag.fingerprint,
+		Begin:         run.ctx.begin,
+		End:           run.ctx.end,
+		Cost:          run.ctx.end.Sub(run.ctx.begin),
+		Pool:          run.opts.Pool,
+		Labels:        run.opts.Labels,
+		Cause:         run.ctx.sanitize(cause),
+		Degraded:      degraded,
+		Sampled:       run.ctx.sampled,
+		Canary:        run.ctx.canary,
+		DataBus:       run.ctx.bus,
+		CheckpointErr: run.ctx.checkpoint.error(),
+		Results:       results,
+		index:         run.dag.index,
+	}
+	result.SLABreaches = run.checkSLA(result)
+	result.PeakConcurrency, result.Concurrency = run.ctx.gauge.snapshot()
+	result.PeakRetrying = int(run.ctx.retrying.peak.Load())
+	if run.ctx.poolStats != nil {
+		result.PoolStats = run.ctx.poolStats.Snapshot()
+	}
+	if parent, ok := run.opts.Parent.(subRunParent); ok {
+		parent.attachSubRun(result)
+	}
+	return result
+}
@@ -1,10 +1,25 @@
 package easydag
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestCycle(t *testing.T) {
@@ -77,3 +92,3479 @@ func BenchmarkPool(b *testing.B) {
 		}
 	})
 }
+
+func TestPanic(t *testing.T) {
+	var cnt int
+	node := &Node[struct{}]{
+		Name:        "node",
+		MaxAttempts: 3,
+		Processor: func(node IRuntimeNode, _ struct{}) error {
+			cnt++
+			panic("boom")
+		},
+	}
+	dag, err := NewDAG(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run(struct{}{}).Results[0]
+	if cnt != 1 || result.Status != Failed || !result.Panicked {
+		t.Fatal("panic should fail node immediately:", cnt, result.Status, result.Panicked)
+	}
+	var panicErr *PanicErr
+	if !errors.As(result.Err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatal("unexpected err:", result.Err)
+	}
+
+	cnt = 0
+	node.RetryOnPanic = true
+	dag, err = NewDAG(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = dag.Run(struct{}{}).Results[0]
+	if cnt != 3 || result.Attempts != 3 || !result.Panicked {
+		t.Fatal("panic should be retried:", cnt, result.Attempts, result.Panicked)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	node1 := &Node[struct{}]{Name: "node1"}
+	node2 := &Node[struct{}]{Name: "node2"}
+	node2.AddDependency(node1)
+	dag, err := NewDAG(node2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = dag.Validate(); err != nil {
+		t.Fatal("unexpected drift:", err)
+	}
+	node1.AddWeakDependency(&Node[struct{}]{Name: "node3"})
+	var driftErr *DriftErr
+	if err = dag.Validate(); !errors.As(err, &driftErr) || len(driftErr.Nodes) != 1 || driftErr.Nodes[0] != "node1" {
+		t.Fatal("drift not detected:", err)
+	}
+
+	// 独立的根节点是合法的，重名节点被报告
+	dag, err = NewDAG(&Node[struct{}]{Name: "single"}, &Node[struct{}]{Name: "dup"}, &Node[struct{}]{Name: "dup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var validationErr *ValidationErr
+	var dupErr *DuplicateNameErr
+	if err = dag.Validate(); !errors.As(err, &validationErr) || len(validationErr.Errs) != 1 || !errors.As(err, &dupErr) || dupErr.Count != 2 {
+		t.Fatal("unexpected validation err:", err)
+	}
+
+	cyclic := &Node[struct{}]{Name: "cyclic"}
+	cyclic.AddDependency(cyclic)
+	var cycleErr *CycleErr
+	if _, err = NewDAG(cyclic); !errors.As(err, &cycleErr) || fmt.Sprint(cycleErr.NodeNames()) != "[cyclic]" {
+		t.Fatal("unexpected cycle err:", err)
+	}
+}
+
+func TestRerunFailed(t *testing.T) {
+	counts := map[string]int{}
+	fail := true
+	newNode := func(name string) *Node[struct{}] {
+		return &Node[struct{}]{
+			Name: name,
+			Processor: func(node IRuntimeNode, _ struct{}) error {
+				counts[node.GetName()]++
+				if node.GetName() == "node2" && fail {
+					return errors.New("failed")
+				}
+				return nil
+			},
+		}
+	}
+	node1, node2, node3 := newNode("node1"), newNode("node2"), newNode("node3")
+	node2.AddDependency(node1)
+	node3.AddDependency(node2)
+	dag, err := NewDAG(node3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := dag.Run(struct{}{})
+	if ByNode(prev, node2) != prev.ByName("node2") || prev.ByName("node2").Status != Failed {
+		t.Fatal("unexpected result of node2:", prev.ByName("node2"))
+	}
+	fail = false
+	result := dag.RerunFailed(prev, struct{}{})
+	for _, r := range result.Results {
+		if r.Status != Succeeded {
+			t.Fatal("node not succeeded:", r.Name, r.Err)
+		}
+		if r.Reused != (r.Name == "node1") {
+			t.Fatal("unexpected reuse:", r.Name)
+		}
+	}
+	if counts["node1"] != 1 || counts["node2"] != 2 || counts["node3"] != 1 {
+		t.Fatal("unexpected counts:", counts)
+	}
+}
+
+func TestRerun(t *testing.T) {
+	var calls atomic.Int32
+	var fail atomic.Bool
+	fail.Store(true)
+	load := &Node[int]{Name: "load", Processor: func(IRuntimeNode, int) error {
+		calls.Add(1)
+		return nil
+	}}
+	save := &Node[int]{Name: "save", Dependencies: []*Node[int]{load}, Processor: func(IRuntimeNode, int) error {
+		if fail.Load() {
+			return errors.New("failed")
+		}
+		return nil
+	}}
+	// 被取消的节点同样重新执行
+	notify := &Node[int]{Name: "notify", Dependencies: []*Node[int]{save}, Processor: func(IRuntimeNode, int) error { return nil }}
+	dag, err := NewDAG(notify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := dag.Run(0)
+	if ByNode(prev, notify).Status == Succeeded {
+		t.Fatal("notify should not run after save failed")
+	}
+	fail.Store(false)
+	result := dag.Rerun(prev, 0)
+	if err := result.Err(); err != nil || calls.Load() != 1 || !ByNode(result, load).Reused || ByNode(result, notify).Status != Succeeded {
+		t.Fatal("unexpected rerun:", err, calls.Load(), ByNode(result, notify).Status)
+	}
+	// 与当前图不匹配的结果不复用
+	if dag.Rerun(&RunResult{}, 0); calls.Load() != 2 {
+		t.Fatal("mismatched result should rerun everything:", calls.Load())
+	}
+}
+
+func TestPoolQueue(t *testing.T) {
+	pool := NewPool(1)
+	block := make(chan struct{})
+	var order []int
+	var wg sync.WaitGroup
+	wg.Add(4)
+	pool.Submit(func() {
+		<-block
+		wg.Done()
+	})
+	// worker 被占用时提交的任务按提交顺序排队执行
+	for i := range 3 {
+		pool.Submit(func() {
+			order = append(order, i)
+			wg.Done()
+		})
+	}
+	close(block)
+	wg.Wait()
+	if fmt.Sprint(order) != "[0 1 2]" {
+		t.Fatal("unexpected order:", order)
+	}
+}
+
+func TestPoolObserver(t *testing.T) {
+	pool := NewPool(1)
+	var stats PoolStats
+	pool.AddObserver(&stats)
+	var nodes []*Node[int]
+	for i := range 3 {
+		nodes = append(nodes, &Node[int]{
+			Name: strconv.Itoa(i),
+			Processor: func(node IRuntimeNode, params int) error {
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			},
+		})
+	}
+	dag, err := NewDAG(nodes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.RunWithOptions(0, RunOptions{Pool: pool})
+	// 单个 worker 依次执行三个节点，后两个任务至少分别等待 10ms 与 20ms
+	if s := result.PoolStats; s.Started != 3 || s.Finished != 2 || s.TotalWait < 30*time.Millisecond {
+		t.Fatal("unexpected run pool stats:", s)
+	}
+	if s := stats.Snapshot(); s.Started != 3 || s.Enqueued != 2 || s.Spawned != 1 {
+		t.Fatal("unexpected pool stats:", s)
+	}
+}
+
+func TestPoolClass(t *testing.T) {
+	pool := NewPool(1)
+	block := make(chan struct{})
+	var order []string
+	var wg sync.WaitGroup
+	wg.Add(3)
+	pool.Submit(func() {
+		<-block
+		wg.Done()
+	})
+	pool.SubmitWithClass(func() {
+		order = append(order, "batch")
+		wg.Done()
+	}, ClassBatch)
+	pool.SubmitWithClass(func() {
+		order = append(order, "interactive")
+		wg.Done()
+	}, ClassInteractive)
+	close(block)
+	wg.Wait()
+	if len(order) != 2 || order[0] != "interactive" || order[1] != "batch" {
+		t.Fatal("unexpected order:", order)
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	node1 := &Node[struct{}]{
+		Name:        "node1",
+		MaxAttempts: 3,
+		BackoffFunc: ConstantBackoff(time.Hour),
+		Processor: func(node IRuntimeNode, _ struct{}) error {
+			time.AfterFunc(10*time.Millisecond, cancel)
+			return errors.New("failed")
+		},
+	}
+	node2 := &Node[struct{}]{Name: "node2", WeakDependencies: []*Node[struct{}]{node1}}
+	node3 := &Node[struct{}]{Name: "node3", Dependencies: []*Node[struct{}]{node2}}
+	dag, err := NewDAG(node3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.RunContext(ctx, struct{}{})
+	if !errors.Is(result.Cause, context.Canceled) {
+		t.Fatal("unexpected cause:", result.Cause)
+	}
+	for _, r := range result.Results {
+		if r.Status != Cancelled || !errors.Is(r.Err, context.Canceled) {
+			t.Fatal("node not cancelled:", r.Name, r.Status, r.Err)
+		}
+	}
+}
+
+func TestProcessorContext(t *testing.T) {
+	cause := make(chan error, 1)
+	node := &Node[struct{}]{
+		Name:         "node",
+		LocalTimeout: 10 * time.Millisecond,
+		Processor: func(node IRuntimeNode, _ struct{}) error {
+			<-node.Context().Done()
+			cause <- context.Cause(node.Context())
+			return nil
+		},
+	}
+	dag, err := NewDAG(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run(struct{}{})
+	if !errors.Is(result.Err(), TimeoutErr) {
+		t.Fatal("node not timed out:", result.Err())
+	}
+	if err := <-cause; !errors.Is(err, TimeoutErr) {
+		t.Fatal("unexpected cause:", err)
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	node1 := &Node[struct{}]{
+		Name: "node1",
+		Processor: func(node IRuntimeNode, _ struct{}) error {
+			return errors.New("failed")
+		},
+	}
+	node2 := &Node[struct{}]{
+		Name: "node2",
+		Processor: func(node IRuntimeNode, _ struct{}) error {
+			<-node.Context().Done()
+			return context.Cause(node.Context())
+		},
+	}
+	node3 := &Node[struct{}]{Name: "node3", Dependencies: []*Node[struct{}]{node2}}
+	dag, err := NewDAG(node1, node3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.RunWithOptions(struct{}{}, RunOptions{FailFast: true})
+	if !errors.Is(result.Err(), ErrFailFast) {
+		t.Fatal("unexpected err:", result.Err())
+	}
+	if r := result.ByName("node3"); r.Status != Cancelled {
+		t.Fatal("node3 not cancelled:", r.Status)
+	}
+}
+
+func TestRunAsync(t *testing.T) {
+	node1 := &Node[struct{}]{
+		Name: "node1",
+		Processor: func(node IRuntimeNode, _ struct{}) error {
+			<-node.Context().Done()
+			return nil
+		},
+	}
+	node2 := &Node[struct{}]{Name: "node2", Dependencies: []*Node[struct{}]{node1}}
+	dag, err := NewDAG(node2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle := dag.RunAsync(struct{}{})
+	if handle.Results() != nil {
+		t.Fatal("results before run finished")
+	}
+	handle.Cancel()
+	result := handle.Wait()
+	if !errors.Is(result.Cause, context.Canceled) || result.ByName("node2").Status != Cancelled {
+		t.Fatal("run not cancelled:", result.Cause)
+	}
+	if handle.Results() != result {
+		t.Fatal("unexpected results")
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	release := make(chan struct{})
+	var ran atomic.Bool
+	node1 := &Node[struct{}]{
+		Name: "node1",
+		Processor: func(node IRuntimeNode, _ struct{}) error {
+			<-release
+			return nil
+		},
+	}
+	node2 := &Node[struct{}]{
+		Name:         "node2",
+		Dependencies: []*Node[struct{}]{node1},
+		Processor: func(node IRuntimeNode, _ struct{}) error {
+			ran.Store(true)
+			return nil
+		},
+	}
+	dag, err := NewDAG(node2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle := dag.RunAsync(struct{}{})
+	handle.Pause()
+	close(release)
+	select {
+	case <-handle.Done():
+		t.Fatal("run finished while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if ran.Load() {
+		t.Fatal("node2 dispatched while paused")
+	}
+	handle.Resume()
+	if err := handle.Wait().Err(); err != nil || !ran.Load() {
+		t.Fatal("run not resumed:", err)
+	}
+}
+
+func TestRunOptions(t *testing.T) {
+	var running, peak atomic.Int32
+	newNode := func(name string) *Node[struct{}] {
+		return &Node[struct{}]{
+			Name: name,
+			Processor: func(node IRuntimeNode, _ struct{}) error {
+				cur := running.Add(1)
+				defer running.Add(-1)
+				for {
+					old := peak.Load()
+					if cur <= old || peak.CompareAndSwap(old, cur) {
+						break
+					}
+				}
+				select {
+				case <-time.After(5 * time.Millisecond):
+				case <-node.Context().Done():
+				}
+				return nil
+			},
+		}
+	}
+	node1, node2, node3 := newNode("node1"), newNode("node2"), newNode("node3")
+	node4 := newNode("node4")
+	node4.AddDependency(node3)
+	dag, err := NewDAG(node1, node2, node4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.RunWithOptions(struct{}{}, RunOptions{MaxConcurrency: 1, Disabled: []string{"node3"}})
+	if peak.Load() != 1 {
+		t.Fatal("unexpected peak concurrency:", peak.Load())
+	}
+	if r := result.ByName("node3"); r.Status != Cancelled || !errors.Is(r.Err, DisabledErr) {
+		t.Fatal("node3 not disabled:", r.Status, r.Err)
+	}
+	if r := result.ByName("node4"); r.Status != Waiting {
+		t.Fatal("node4 should not run:", r.Status)
+	}
+	result = dag.RunWithOptions(struct{}{}, RunOptions{Timeout: time.Millisecond, MaxConcurrency: 1})
+	if !errors.Is(result.Err(), TimeoutErr) {
+		t.Fatal("run not timed out:", result.Err())
+	}
+}
+
+func TestCondition(t *testing.T) {
+	never := func(IRuntimeNode, struct{}) bool { return false }
+	node1 := &Node[struct{}]{Name: "node1", Condition: never}
+	node2 := &Node[struct{}]{Name: "node2", Dependencies: []*Node[struct{}]{node1}}
+	node3 := &Node[struct{}]{Name: "node3", Dependencies: []*Node[struct{}]{node1}, SkipPolicy: SkipAsSatisfied}
+	node4 := &Node[struct{}]{Name: "node4", WeakDependencies: []*Node[struct{}]{node1}}
+	dag, err := NewDAG(node2, node3, node4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run(struct{}{})
+	expected := map[string]NodeStatus{"node1": Skipped, "node2": Skipped, "node3": Succeeded, "node4": Succeeded}
+	for name, status := range expected {
+		if r := result.ByName(name); r.Status != status || r.Degraded {
+			t.Fatal("unexpected status:", name, r.Status, r.Degraded)
+		}
+	}
+	if result.Err() != nil {
+		t.Fatal("unexpected err:", result.Err())
+	}
+}
+
+func TestDependencyIf(t *testing.T) {
+	root := &Node[bool]{Name: "root"}
+	left := &Node[bool]{Name: "left"}
+	right := &Node[bool]{Name: "right"}
+	merge := &Node[bool]{Name: "merge", SkipPolicy: SkipAsSatisfied}
+	left.AddDependencyIf(root, func(params bool) bool { return params })
+	right.AddDependencyIf(root, func(params bool) bool { return !params })
+	merge.AddDependency(left, right)
+	dag, err := NewDAG(merge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run(true)
+	expected := map[string]NodeStatus{"root": Succeeded, "left": Succeeded, "right": Skipped, "merge": Succeeded}
+	for name, status := range expected {
+		if r := result.ByName(name); r.Status != status {
+			t.Fatal("unexpected status:", name, r.Status)
+		}
+	}
+
+	// 边条件发生 panic 时子节点失败，而不是被跳过
+	child := &Node[bool]{Name: "child"}
+	child.AddDependencyIf(root, func(params bool) bool { panic("bad predicate") })
+	dag, err = NewDAG(child)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var panicErr *PanicErr
+	if r := dag.Run(true).ByName("child"); r.Status != Failed || !errors.As(r.Err, &panicErr) || !r.Panicked {
+		t.Fatal("panicking predicate should fail the child:", r.Status, r.Err)
+	}
+}
+
+func TestRouterNode(t *testing.T) {
+	route := NewRouterNode("route", func(node IRuntimeNode, params string) ([]string, error) {
+		return []string{params}, nil
+	})
+	left := &Node[string]{Name: "left", Dependencies: []*Node[string]{route}}
+	right := &Node[string]{Name: "right", Dependencies: []*Node[string]{route}, SkipPolicy: SkipAsSatisfied}
+	dag, err := NewDAG(left, right)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run("left")
+	if result.ByName("left").Status != Succeeded || result.ByName("right").Status != Skipped {
+		t.Fatal("unexpected route:", result.ByName("left").Status, result.ByName("right").Status)
+	}
+
+	// 中间件替换了 IRuntimeNode 时路由节点失败，而不是激活所有分支
+	type wrapped struct{ IRuntimeNode }
+	wrap := func(next Processor[string]) Processor[string] {
+		return func(node IRuntimeNode, params string) error {
+			return next(wrapped{node}, params)
+		}
+	}
+	dag, err = NewDAGWithOptions(BuildOptions[string]{Middlewares: []Middleware[string]{wrap}}, left, right)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = dag.Run("left")
+	if r := result.ByName("route"); r.Status != Failed || !errors.Is(r.Err, UnroutableErr) || result.ByName("left").Status == Succeeded {
+		t.Fatal("unexpected wrapped route:", r.Status, r.Err)
+	}
+}
+
+func TestSubDAGNode(t *testing.T) {
+	inner := &Node[struct{}]{
+		Name: "inner",
+		Processor: func(node IRuntimeNode, _ struct{}) error {
+			return errors.New("failed")
+		},
+	}
+	sub, err := NewDAG(inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dag, err := NewDAG(NewSubDAGNode("sub", sub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run(struct{}{})
+	var names []string
+	for r := range result.Flatten() {
+		names = append(names, r.Name)
+	}
+	if result.ByName("sub").Status != Failed || fmt.Sprint(names) != "[sub sub/inner]" {
+		t.Fatal("unexpected results:", result.ByName("sub").Status, names)
+	}
+	if r := result.ByName("sub/inner"); r == nil || r.Status != Failed {
+		t.Fatal("sub-DAG node should be found by prefixed name:", r)
+	}
+
+	// 子图继承父运行的 EventSink 与 ErrSanitizer
+	var mu sync.Mutex
+	var nodes []string
+	sink := EventSinkFunc(func(event *RunEvent) {
+		if event.Type == EventNodeFinished {
+			mu.Lock()
+			nodes = append(nodes, event.Node)
+			mu.Unlock()
+		}
+	})
+	sanitizer := func(err error) error {
+		return Redact(err, func(string) string { return "***" })
+	}
+	result = dag.RunWithOptions(struct{}{}, RunOptions{EventSink: sink, ErrSanitizer: sanitizer})
+	if r := result.ByName("sub/inner"); r.Err == nil || r.Err.Error() != "***" {
+		t.Fatal("sub-DAG error should be sanitized:", r.Err)
+	}
+	if fmt.Sprint(nodes) != "[inner sub]" {
+		t.Fatal("sub-DAG events should reach the parent sink:", nodes)
+	}
+
+	// 父运行设置了 FailFast 时，子图内的节点失败同样终止子图
+	after := &Node[struct{}]{Name: "after", WeakDependencies: []*Node[struct{}]{inner}}
+	sub, err = NewDAG(after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dag, err = NewDAG(NewSubDAGNode("sub", sub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := dag.RunWithOptions(struct{}{}, RunOptions{FailFast: true}).ByName("sub/after"); r.Status != Cancelled {
+		t.Fatal("sub-DAG should inherit FailFast:", r.Status)
+	}
+}
+
+func TestMapNode(t *testing.T) {
+	var sum atomic.Int64
+	node := NewMapNode("map", func(params []int) []int { return params }, func(node IRuntimeNode, _ []int, item int) error {
+		if item < 0 {
+			return errors.New("negative")
+		}
+		sum.Add(int64(item))
+		return nil
+	}, 2)
+	dag, err := NewDAG(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run([]int{1, 2, -1, 3})
+	var mapErr *MapErr
+	if !errors.As(result.Err(), &mapErr) || fmt.Sprint(mapErr.Items) != "[2]" || sum.Load() != 6 {
+		t.Fatal("unexpected result:", result.Err(), sum.Load())
+	}
+}
+
+func TestMapReduceNodes(t *testing.T) {
+	var joined string
+	mapNode, reduceNode := NewMapReduceNodes("square", func(params []int) []int { return params },
+		func(node IRuntimeNode, _ []int, item int) (int, error) {
+			time.Sleep(time.Duration(10-item) * time.Millisecond)
+			return item * item, nil
+		},
+		func(node IRuntimeNode, _ []int, outputs []int) error {
+			joined = fmt.Sprint(outputs)
+			return nil
+		}, 0)
+	dag, err := NewDAG(mapNode, reduceNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run([]int{1, 2, 3})
+	if err := result.Err(); err != nil || joined != "[1 4 9]" {
+		t.Fatal("unexpected reduce:", err, joined)
+	}
+	if output := fmt.Sprint(result.ByName("square").Output); output != "[1 4 9]" {
+		t.Fatal("map outputs should be the map node's output:", output)
+	}
+}
+
+func TestDataNode(t *testing.T) {
+	count := NewDataNode("count", func(node IRuntimeNode, params string) (int, error) {
+		return len(params), nil
+	})
+	double := NewDataNode("double", func(node IRuntimeNode, _ string) (int, error) {
+		n, ok := InputOf[int](node, "count")
+		if !ok {
+			return 0, errors.New("missing input")
+		}
+		return n * 2, nil
+	})
+	double.AddDependency(count)
+	dag, err := NewDAG(double)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run("abc")
+	if output := ByNode(result, double).Output; output != 6 {
+		t.Fatal("unexpected output:", output, result.Err())
+	}
+}
+
+func TestSLA(t *testing.T) {
+	node := &Node[int]{
+		Name: "flaky",
+		SLA:  NodeSLA{MaxFailureRate: 0.5},
+		Processor: func(node IRuntimeNode, params int) error {
+			if params > 0 {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+	dag, err := NewDAG(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if breaches := dag.Run(0).SLABreaches; len(breaches) != 0 {
+		t.Fatal("unexpected breaches:", breaches)
+	}
+	if breaches := dag.Run(1).SLABreaches; len(breaches) != 0 {
+		t.Fatal("unexpected breaches:", breaches)
+	}
+	breaches := dag.Run(1).SLABreaches
+	if len(breaches) != 1 || breaches[0].Kind != BreachNodeFailureRate || breaches[0].Node != "flaky" {
+		t.Fatal("unexpected breaches:", breaches)
+	}
+
+	slow := &Node[int]{
+		Name: "slow",
+		Processor: func(node IRuntimeNode, params int) error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		},
+	}
+	dag, err = NewDAGWithOptions(BuildOptions[int]{SLA: time.Millisecond}, slow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other, _ := NewDAG(slow); other.Fingerprint() == dag.Fingerprint() {
+		t.Fatal("SLA not included in fingerprint")
+	}
+	var events []RunEventType
+	var breach *SLABreach
+	sink := EventSinkFunc(func(event *RunEvent) {
+		events = append(events, event.Type)
+		if event.Type == EventSLABreached {
+			breach = event.Breach
+		}
+	})
+	breaches = dag.RunWithOptions(0, RunOptions{EventSink: sink}).SLABreaches
+	if len(breaches) != 1 || breaches[0].Kind != BreachRunDuration {
+		t.Fatal("unexpected breaches:", breaches)
+	}
+	if breach == nil || *breach != breaches[0] {
+		t.Fatal("unexpected breach event:", breach)
+	}
+	if len(events) < 2 || events[len(events)-2] != EventSLABreached || events[len(events)-1] != EventRunFinished {
+		t.Fatal("unexpected events:", events)
+	}
+
+	// 复用的结果保留历史耗时，但节点未被执行，不计入耗时违约
+	costly := &Node[int]{
+		Name: "costly",
+		SLA:  NodeSLA{MaxCost: time.Millisecond},
+		Processor: func(node IRuntimeNode, params int) error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		},
+	}
+	dag, err = NewDAG(costly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := dag.Run(0)
+	if len(prev.SLABreaches) != 1 || prev.SLABreaches[0].Kind != BreachNodeCost {
+		t.Fatal("unexpected breaches:", prev.SLABreaches)
+	}
+	result := dag.Rerun(prev, 0)
+	if r := ByNode(result, costly); !r.Reused || r.Cost <= time.Millisecond || len(result.SLABreaches) != 0 {
+		t.Fatal("reused result should not breach:", r.Reused, r.Cost, result.SLABreaches)
+	}
+}
+
+func TestDataBus(t *testing.T) {
+	key := NewBusKey[int]("count")
+	publish := &Node[string]{
+		Name: "publish",
+		Processor: func(node IRuntimeNode, params string) error {
+			if !Publish(node, key, len(params)) {
+				return errors.New("publish rejected")
+			}
+			return nil
+		},
+	}
+	late := &Node[string]{
+		Name:         "late",
+		LocalTimeout: 10 * time.Millisecond,
+		Processor: func(node IRuntimeNode, params string) error {
+			for node.GetStatus() == Running {
+				time.Sleep(time.Millisecond)
+			}
+			Publish(node, NewBusKey[string]("late"), params)
+			return nil
+		},
+	}
+	dag, err := NewDAG(publish, late)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run("abc")
+	if n, ok := Lookup(result.DataBus, key); !ok || n != 3 {
+		t.Fatal("unexpected value:", n, ok)
+	}
+	if _, ok := Lookup(result.DataBus, NewBusKey[string]("late")); ok {
+		t.Fatal("write after timeout should be rejected")
+	}
+}
+
+func TestMemoization(t *testing.T) {
+	var calls atomic.Int32
+	square := NewDataNode("square", func(node IRuntimeNode, params int) (int, error) {
+		calls.Add(1)
+		return params * params, nil
+	})
+	square.Cache = NewMemoryCache(time.Minute)
+	square.CacheKey = func(params int) string {
+		return strconv.Itoa(params)
+	}
+	dag, err := NewDAG(square)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, params := range []int{3, 3, 4} {
+		r := ByNode(dag.Run(params), square)
+		if r.Output != params*params {
+			t.Fatal("unexpected output:", r.Output)
+		}
+	}
+	if calls.Load() != 2 || !ByNode(dag.Run(4), square).CacheHit {
+		t.Fatal("unexpected calls:", calls.Load())
+	}
+
+	// 没有输出的节点把结果写入 params，不能被缓存跳过
+	double := &Node[*int]{
+		Name:     "double",
+		Cache:    NewMemoryCache(time.Minute),
+		CacheKey: func(params *int) string { return strconv.Itoa(*params) },
+		Processor: func(node IRuntimeNode, params *int) error {
+			*params *= 2
+			return nil
+		},
+	}
+	sideEffect, err := NewDAG(double)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		params := 3
+		if r := ByNode(sideEffect.Run(&params), double); r.CacheHit || params != 6 {
+			t.Fatal("node without output should not be memoized:", r.CacheHit, params)
+		}
+	}
+}
+
+func TestRunShared(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	node := &Node[int]{
+		Name: "slow",
+		Processor: func(node IRuntimeNode, params int) error {
+			calls.Add(1)
+			<-release
+			return nil
+		},
+	}
+	dag, err := NewDAGWithOptions(BuildOptions[int]{DedupKey: strconv.Itoa}, node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	var shared atomic.Int32
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := dag.RunShared(1); ok {
+				shared.Add(1)
+			}
+		}()
+	}
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// 等待其余调用方加入进行中的运行
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 || shared.Load() != 3 {
+		t.Fatal("unexpected calls:", calls.Load(), shared.Load())
+	}
+}
+
+func TestRunWarm(t *testing.T) {
+	var calls atomic.Int32
+	load := NewDataNode("load", func(node IRuntimeNode, params int) (int, error) {
+		calls.Add(1)
+		return params + 1, nil
+	})
+	load.CacheKey = func(params int) string {
+		return strconv.Itoa(params)
+	}
+	dag, err := NewDAG(load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := dag.Run(1)
+	warm := dag.RunWarm(prev, 1, time.Minute)
+	if r := ByNode(warm, load); !r.Reused || r.Output != 2 || calls.Load() != 1 {
+		t.Fatal("expected reused result:", r.Reused, r.Output, calls.Load())
+	}
+	if r := ByNode(dag.RunWarm(warm, 2, time.Minute), load); r.Reused || r.Output != 3 {
+		t.Fatal("changed input should rerun:", r.Reused, r.Output)
+	}
+	if r := ByNode(dag.RunWarm(prev, 1, 0), load); r.Reused {
+		t.Fatal("stale result should rerun")
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	store, err := NewFileCheckpointStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls atomic.Int32
+	fail := true
+	load := NewDataNode("load", func(node IRuntimeNode, params int) (int, error) {
+		calls.Add(1)
+		return params * 10, nil
+	})
+	save := NewDataNode("save", func(node IRuntimeNode, params int) (int, error) {
+		if fail {
+			return 0, errors.New("crashed")
+		}
+		n, ok := InputOf[int](node, "load")
+		if !ok {
+			return 0, errors.New("missing input")
+		}
+		return n + 1, nil
+	})
+	save.AddDependency(load)
+	dag, err := NewDAG(save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := RunOptions{RunID: "job", Checkpoint: store}
+	if result := dag.RunWithOptions(1, opts); result.Err() == nil || result.CheckpointErr != nil {
+		t.Fatal("expected failure:", result.CheckpointErr)
+	}
+	fail = false
+	result, err := dag.Resume("job", 1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := ByNode(result, save); r.Status != Succeeded || r.Output != 11 || calls.Load() != 1 {
+		t.Fatal("unexpected resume:", r.Status, r.Output, r.Err, calls.Load())
+	}
+}
+
+func TestNewPoolAuto(t *testing.T) {
+	procs := runtime.GOMAXPROCS(0)
+	if n := NewPoolAuto(PoolAutoOptions{Workload: WorkloadCPU}).MaxWorkers(); n != procs {
+		t.Fatal("unexpected cpu workers:", n)
+	}
+	if n := NewPoolAuto(PoolAutoOptions{IOMultiplier: 4, MaxWorkers: 2}).MaxWorkers(); n != min(4*procs, 2) {
+		t.Fatal("unexpected io workers:", n)
+	}
+}
+
+func TestRunArchive(t *testing.T) {
+	dag, err := NewDAG(&Node[int]{Name: "noop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := NewRunArchive(RetentionPolicy{KeepLast: 2})
+	defer archive.Close()
+	for i := range 5 {
+		archive.Add(dag.Run(i))
+	}
+	daily := archive.Daily()
+	if len(archive.Runs()) != 2 || len(daily) == 0 || daily[len(daily)-1].Runs == 0 {
+		t.Fatal("unexpected archive:", len(archive.Runs()), daily)
+	}
+
+	// 按天汇总，并清理早于 MaxDays 的统计，即使统计的条数未超过 MaxDays
+	now := time.Now().UTC()
+	run := func(daysAgo int, cost time.Duration, cause error) *RunResult {
+		return &RunResult{Begin: now.AddDate(0, 0, -daysAgo), Cost: cost, Cause: cause}
+	}
+	archive = NewRunArchive(RetentionPolicy{KeepLast: 1, MaxDays: 3})
+	defer archive.Close()
+	archive.Add(run(10, time.Second, nil))
+	archive.Add(run(1, time.Second, nil))
+	archive.Add(run(1, 3*time.Second, errors.New("boom")))
+	archive.Add(run(0, time.Second, nil))
+	daily = archive.Daily()
+	if len(daily) != 1 || len(archive.Runs()) != 1 {
+		t.Fatal("unexpected retention:", daily, len(archive.Runs()))
+	}
+	if d := daily[0]; d.Date != now.AddDate(0, 0, -1).Format(time.DateOnly) || d.Runs != 2 || d.Failed != 1 || d.TotalCost != 4*time.Second || d.MaxCost != 3*time.Second {
+		t.Fatal("unexpected rollup:", d)
+	}
+
+	// 后台清理协程定期压缩，Close 后停止
+	archive = NewRunArchive(RetentionPolicy{KeepLast: 1, Interval: time.Millisecond})
+	archive.Add(run(0, time.Second, nil))
+	archive.Add(run(0, time.Second, nil))
+	deadline := time.Now().Add(5 * time.Second)
+	for len(archive.Runs()) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("janitor did not compact")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	archive.Close()
+	archive.Close()
+	time.Sleep(5 * time.Millisecond)
+	archive.Add(run(0, time.Second, nil))
+	time.Sleep(20 * time.Millisecond)
+	if len(archive.Runs()) != 2 {
+		t.Fatal("janitor should stop after Close")
+	}
+}
+
+func TestRunTargets(t *testing.T) {
+	var ran sync.Map
+	newNode := func(name string, deps ...*Node[int]) *Node[int] {
+		return &Node[int]{
+			Name:         name,
+			Dependencies: deps,
+			Processor: func(node IRuntimeNode, params int) error {
+				ran.Store(name, true)
+				return nil
+			},
+		}
+	}
+	a := newNode("a")
+	b := newNode("b", a)
+	c := newNode("c", a)
+	d := newNode("d", b, c)
+	dag, err := NewDAG(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.RunTargets(0, b)
+	if ByNode(result, b).Status != Succeeded || ByNode(result, c).Status != Skipped || ByNode(result, d).Status != Skipped {
+		t.Fatal("unexpected result:", result.Err())
+	}
+	if _, ok := ran.Load("c"); ok {
+		t.Fatal("pruned node should not run")
+	}
+
+	// 目标均不属于该图时不应执行整个图
+	foreign := newNode("foreign")
+	for _, result := range []*RunResult{
+		dag.RunTargets(0, foreign),
+		dag.RunWithOptions(0, RunOptions{Targets: []string{"typo"}}),
+	} {
+		for r := range result.All() {
+			if r.Status != Skipped {
+				t.Fatal("unresolved targets should skip every node:", r.Name, r.Status)
+			}
+		}
+	}
+	if _, ok := ran.Load("d"); ok {
+		t.Fatal("unresolved targets should not run the graph")
+	}
+}
+
+func TestAnchorNode(t *testing.T) {
+	var sum atomic.Int32
+	newNode := func(n int32, deps ...*Node[int]) *Node[int] {
+		return &Node[int]{
+			Dependencies: deps,
+			Processor: func(node IRuntimeNode, params int) error {
+				sum.Add(n)
+				return nil
+			},
+		}
+	}
+	group := NewAnchorNode("group", newNode(1), newNode(2))
+	last := newNode(4, NewAnchorNode("join", group))
+	dag, err := NewDAG(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dag.Run(0).Err(); err != nil || sum.Load() != 7 {
+		t.Fatal("unexpected result:", err, sum.Load())
+	}
+
+	// 设置了执行相关配置的空节点不是锚点
+	for _, node := range []*Node[int]{
+		{Name: "hooked", OnStart: func(IRuntimeNode, int) {}},
+		{Name: "exclusive", Exclusive: true},
+		{Name: "limited", MaxConcurrent: 1},
+		{Name: "resource", Resources: []string{"db"}},
+	} {
+		if isAnchor(node) {
+			t.Fatal("node should not be an anchor:", node.Name)
+		}
+	}
+	if !isAnchor(NewAnchorNode[int]("anchor")) {
+		t.Fatal("NewAnchorNode should create an anchor")
+	}
+}
+
+func TestCanary(t *testing.T) {
+	node := &Node[*string]{
+		Name: "impl",
+		Processor: func(node IRuntimeNode, params *string) error {
+			*params = "baseline"
+			return nil
+		},
+		Canary: func(node IRuntimeNode, params *string) error {
+			*params = "canary"
+			return nil
+		},
+	}
+	dag, err := NewDAG(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rate := range []float64{0, 1} {
+		var impl string
+		result := dag.RunWithOptions(&impl, RunOptions{CanaryRate: rate})
+		if variant := ByNode(result, node).Variant; variant != impl || result.Canary != (rate == 1) {
+			t.Fatal("unexpected variant:", variant, impl)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	common := &Node[int]{Name: "common"}
+	pro := &Node[int]{Name: "pro", Tags: []string{"pro"}, Dependencies: []*Node[int]{common}}
+	lite := &Node[int]{Name: "lite", Tags: []string{"lite"}, Dependencies: []*Node[int]{common}}
+	report := &Node[int]{Name: "report", SkipPolicy: SkipAsSatisfied, Dependencies: []*Node[int]{pro, lite}}
+	dag, err := NewDAG(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.RunWithOptions(0, RunOptions{IncludeTags: []string{"pro"}})
+	for node, status := range map[*Node[int]]NodeStatus{common: Succeeded, pro: Succeeded, lite: Skipped, report: Succeeded} {
+		if r := ByNode(result, node); r.Status != status {
+			t.Fatal("unexpected status:", r.Name, r.Status)
+		}
+	}
+}
+
+func TestStealGroup(t *testing.T) {
+	busy, idle := NewPool(1), NewPool(1)
+	var busyStats, idleStats PoolStats
+	busy.AddObserver(&busyStats)
+	idle.AddObserver(&idleStats)
+	NewStealGroup(busy, idle)
+	release := make(chan struct{})
+	stolen := make(chan struct{})
+	var pinnedRan atomic.Bool
+	busy.Submit(func() { <-release })
+	busy.SubmitPinned(func() { pinnedRan.Store(true) }, ClassInteractive)
+	// 空闲的协程池没有提交过任务，也应被唤醒窃取
+	busy.Submit(func() { close(stolen) })
+	select {
+	case <-stolen:
+	case <-time.After(time.Second):
+		t.Fatal("queued task was not stolen")
+	}
+	if pinnedRan.Load() {
+		t.Fatal("pinned task should not be stolen")
+	}
+	close(release)
+	for busy.QueueLen() > 0 || busyStats.Snapshot().Finished < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	if !pinnedRan.Load() {
+		t.Fatal("pinned task should run on its own pool")
+	}
+	// 被窃取的任务计入原协程池的观测回调
+	if busySnap, idleSnap := busyStats.Snapshot(), idleStats.Snapshot(); busySnap.Started != 3 || idleSnap.Started != 0 || idleSnap.Spawned != 1 {
+		t.Fatal("unexpected stats:", busySnap, idleSnap)
+	}
+
+	// NoSteal 不是标签，不参与标签过滤
+	node := &Node[int]{Name: "pinned", NoSteal: true, Tags: []string{"pro"}}
+	dag, err := NewDAG(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := dag.RunWithOptions(0, RunOptions{Pool: busy, IncludeTags: []string{"pro"}}); r.ByName("pinned").Status != Succeeded {
+		t.Fatal("pinned node should not be filtered")
+	}
+}
+
+func TestOnStart(t *testing.T) {
+	var attempts, retries []uint
+	node := &Node[int]{
+		Name:        "retry",
+		MaxAttempts: 3,
+		OnStart: func(node IRuntimeNode, params int) {
+			attempts = append(attempts, node.GetAttempts())
+		},
+		OnRetry: func(node IRuntimeNode, params int, attempt uint, err error) {
+			retries = append(retries, attempt)
+		},
+		Processor: func(node IRuntimeNode, params int) error {
+			return errors.New("failed")
+		},
+	}
+	dag, err := NewDAG(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dag.Run(0)
+	if fmt.Sprint(attempts) != "[1 2 3]" || fmt.Sprint(retries) != "[1 2]" {
+		t.Fatal("unexpected attempts:", attempts, retries)
+	}
+}
+
+func TestKill(t *testing.T) {
+	// 无论是否设置超时，被终止节点的上下文都应被取消
+	for _, timeout := range []time.Duration{time.Minute, 0} {
+		started := make(chan struct{})
+		cause := make(chan error, 1)
+		stuck := &Node[int]{
+			Name:         "stuck",
+			LocalTimeout: timeout,
+			Processor: func(node IRuntimeNode, params int) error {
+				close(started)
+				<-node.Context().Done()
+				cause <- context.Cause(node.Context())
+				return node.Context().Err()
+			},
+		}
+		after := &Node[int]{Name: "after"}
+		after.AddWeakDependency(stuck)
+		dag, err := NewDAG(after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handle := dag.RunAsync(0)
+		<-started
+		if !handle.Kill("stuck") || handle.Kill("stuck") {
+			t.Fatal("node should be killed exactly once")
+		}
+		result := handle.Wait()
+		if r := ByNode(result, stuck); r.Status != Failed || !errors.Is(r.Err, OperatorKilledErr) {
+			t.Fatal("unexpected result:", timeout, r.Status, r.Err)
+		}
+		if ByNode(result, after).Status != Succeeded {
+			t.Fatal("weak child should still run")
+		}
+		if err := <-cause; !errors.Is(err, OperatorKilledErr) {
+			t.Fatal("unexpected context cause:", timeout, err)
+		}
+	}
+}
+
+func TestAllOfAnyOf(t *testing.T) {
+	newDAG := func(delay time.Duration, fail bool) *DAG[int] {
+		dag, err := NewDAG(&Node[int]{
+			Name: "work",
+			Processor: func(node IRuntimeNode, params int) error {
+				select {
+				case <-time.After(delay):
+				case <-node.Context().Done():
+				}
+				if fail {
+					return errors.New("failed")
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dag
+	}
+	ok, bad := newDAG(0, false), newDAG(0, true)
+	results, err := AllOf(ok.RunAsync(0), bad.RunAsync(0))
+	if len(results) != 2 || results[0].Err() != nil || err == nil {
+		t.Fatal("unexpected AllOf:", err)
+	}
+	slow := newDAG(time.Minute, false)
+	slowHandle := slow.RunAsync(0)
+	idx, result, err := AnyOf(bad.RunAsync(0), slowHandle, ok.RunAsync(0))
+	if idx != 2 || result == nil || err != nil {
+		t.Fatal("unexpected AnyOf:", idx, err)
+	}
+	if !errors.Is(slowHandle.Wait().Cause, context.Canceled) {
+		t.Fatal("other runs should be cancelled")
+	}
+}
+
+func TestExclusiveLease(t *testing.T) {
+	var running, peak atomic.Int32
+	node := &Node[int]{
+		Name:      "exclusive",
+		Exclusive: true,
+		Processor: func(node IRuntimeNode, params int) error {
+			n := running.Add(1)
+			if n > peak.Load() {
+				peak.Store(n)
+			}
+			time.Sleep(20 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		},
+	}
+	dag, err := NewDAGWithOptions(BuildOptions[int]{Name: "jobs"}, node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &rejectingLeaseStore{MemoryLeaseStore: NewMemoryLeaseStore()}
+	// 相同的 LeaseHolder 不应使并发运行共享租约
+	opts := RunOptions{Leases: store, LeaseHolder: "host-1"}
+	var handles []*RunHandle
+	for range 3 {
+		handles = append(handles, dag.RunAsyncWithOptions(0, opts))
+	}
+	if _, err := AllOf(handles...); err != nil || peak.Load() != 1 {
+		t.Fatal("unexpected peak:", err, peak.Load())
+	}
+	if key := store.lastKey.Load(); key == nil || *key != "easydag/lease/jobs/exclusive" {
+		t.Fatal("unexpected lease key:", key)
+	}
+
+	// 续约失败时节点以 LeaseLostErr 失败，且上下文被取消
+	store.reject.Store(true)
+	causes := make(chan error, 1)
+	long := &Node[int]{
+		Name:         "long",
+		Exclusive:    true,
+		LocalTimeout: time.Second,
+		Processor: func(node IRuntimeNode, _ int) error {
+			<-node.Context().Done()
+			causes <- context.Cause(node.Context())
+			return nil
+		},
+	}
+	dag, err = NewDAG(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.RunWithOptions(0, RunOptions{Leases: store, LeaseTTL: 30 * time.Millisecond})
+	if r := result.ByName("long"); r.Status != Failed || !errors.Is(r.Err, LeaseLostErr) || r.Cost > 500*time.Millisecond {
+		t.Fatal("unexpected lease lost result:", r.Status, r.Err, r.Cost)
+	}
+	// 节点被终止后运行即结束，不等待 processor 返回
+	if cause := <-causes; cause != LeaseLostErr {
+		t.Fatal("unexpected context cause:", cause)
+	}
+
+	// 等待租约期间不占用并发名额，其他节点照常执行
+	store = &rejectingLeaseStore{MemoryLeaseStore: NewMemoryLeaseStore()}
+	if ok, _ := store.TryAcquire(context.Background(), "easydag/lease/jobs/locked", "other-host", 200*time.Millisecond); !ok {
+		t.Fatal("failed to hold lease")
+	}
+	locked := &Node[int]{Name: "locked", Exclusive: true, Processor: func(IRuntimeNode, int) error { return nil }}
+	gate := &Node[int]{Name: "gate", Processor: func(IRuntimeNode, int) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}}
+	free := &Node[int]{Name: "free", Dependencies: []*Node[int]{gate}, Processor: func(IRuntimeNode, int) error { return nil }}
+	dag, err = NewDAGWithOptions(BuildOptions[int]{Name: "jobs"}, locked, free)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = dag.RunWithOptions(0, RunOptions{Leases: store, MaxConcurrency: 1})
+	if r := result.ByName("free"); r.Status != Succeeded || r.Begin.Sub(result.Begin) > 100*time.Millisecond {
+		t.Fatal("free node blocked by lease wait:", r.Status, r.Begin.Sub(result.Begin))
+	}
+	if r := result.ByName("locked"); r.Status != Succeeded {
+		t.Fatal("unexpected locked result:", r.Status, r.Err)
+	}
+
+	// 极短的 LeaseTTL 不会使续约间隔为0
+	result = dag.RunWithOptions(0, RunOptions{Leases: NewMemoryLeaseStore(), LeaseTTL: time.Nanosecond})
+	if r := result.ByName("free"); r.Status != Succeeded {
+		t.Fatal("unexpected result with tiny ttl:", r.Status)
+	}
+}
+
+// rejectingLeaseStore 记录租约键，reject 为 true 时拒绝续约
+type rejectingLeaseStore struct {
+	*MemoryLeaseStore
+	lastKey atomic.Pointer[string]
+	reject  atomic.Bool
+}
+
+func (s *rejectingLeaseStore) TryAcquire(ctx context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	s.lastKey.Store(&key)
+	return s.MemoryLeaseStore.TryAcquire(ctx, key, holder, ttl)
+}
+
+func (s *rejectingLeaseStore) Renew(ctx context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	if s.reject.Load() {
+		return false, nil
+	}
+	return s.MemoryLeaseStore.Renew(ctx, key, holder, ttl)
+}
+
+func TestRunHooks(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}
+	hooks := &RunHooks{
+		OnRunStart: func(runID string) {
+			record("start")
+		},
+		OnRunFinish: func(result *RunResult) {
+			record("finish")
+		},
+		OnNodeStateChange: func(runID string, node string, from, to NodeStatus) {
+			record(node + ":" + to.String())
+		},
+	}
+	dag, err := NewDAGWithOptions(BuildOptions[int]{Hooks: hooks}, &Node[int]{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dag.Run(0)
+	if got := fmt.Sprint(events); got != "[start a:running a:succeeded finish]" {
+		t.Fatal("unexpected events:", got)
+	}
+
+	// 异步运行不获取结果也会回调，且多次获取结果只回调一次
+	events = nil
+	handle := dag.RunAsync(0)
+	<-handle.Done()
+	handle.Wait()
+	handle.Wait()
+	if got := fmt.Sprint(events); got != "[start a:running a:succeeded finish]" {
+		t.Fatal("unexpected async events:", got)
+	}
+}
+
+func TestAdmission(t *testing.T) {
+	var asked atomic.Int32
+	dag, err := NewDAG(
+		&Node[int]{Name: "a", Processor: func(IRuntimeNode, int) error { return nil }},
+		&Node[int]{Name: "b", Processor: func(IRuntimeNode, int) error { return nil }},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admission := AdmissionFunc(func(node IRuntimeNode) AdmissionDecision {
+		if node.GetName() == "b" {
+			return AdmissionDecision{Action: AdmissionSkip, Reason: "over budget"}
+		}
+		if asked.Add(1) == 1 {
+			return AdmissionDecision{Action: AdmissionDelay, Delay: time.Millisecond}
+		}
+		return AdmissionDecision{Action: AdmissionAllow}
+	})
+	result := dag.RunWithOptions(0, RunOptions{Admission: admission})
+	if a := result.ByName("a"); a.Status != Succeeded || asked.Load() != 2 {
+		t.Fatal("unexpected a:", a.Status, asked.Load())
+	}
+	var admissionErr *AdmissionErr
+	if b := result.ByName("b"); b.Status != Skipped || !errors.As(b.Err, &admissionErr) || admissionErr.Reason != "over budget" {
+		t.Fatal("unexpected b:", b.Status, b.Err)
+	}
+
+	// 推迟执行的节点不占用唯一的 worker
+	var order []string
+	var mu sync.Mutex
+	record := func(node IRuntimeNode, _ int) error {
+		mu.Lock()
+		order = append(order, node.GetName())
+		mu.Unlock()
+		return nil
+	}
+	dag, err = NewDAG(&Node[int]{Name: "delayed", Processor: record}, &Node[int]{Name: "other", Processor: record})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var delayed atomic.Bool
+	admission = AdmissionFunc(func(node IRuntimeNode) AdmissionDecision {
+		if node.GetName() == "delayed" && !delayed.Swap(true) {
+			return AdmissionDecision{Action: AdmissionDelay, Delay: 50 * time.Millisecond}
+		}
+		return AdmissionDecision{Action: AdmissionAllow}
+	})
+	if err := dag.RunWithOptions(0, RunOptions{Pool: NewPool(1), Admission: admission}).Err(); err != nil || fmt.Sprint(order) != "[other delayed]" {
+		t.Fatal("delayed node should release the worker:", err, order)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	var wraps atomic.Int32
+	trace := func(name string) Middleware[int] {
+		return func(next Processor[int]) Processor[int] {
+			wraps.Add(1)
+			return func(node IRuntimeNode, params int) error {
+				mu.Lock()
+				calls = append(calls, name+":"+node.GetName())
+				mu.Unlock()
+				return next(node, params)
+			}
+		}
+	}
+	a := &Node[int]{Name: "a", Processor: func(IRuntimeNode, int) error { return nil }}
+	b := &Node[int]{Name: "b", Dependencies: []*Node[int]{a}, Processor: func(IRuntimeNode, int) error { return nil }}
+	dag, err := NewDAGWithOptions(BuildOptions[int]{Middlewares: []Middleware[int]{trace("outer")}}, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Use 注册的中间件位于构建配置的中间件内层
+	if err := dag.Use(trace("inner")); err != nil {
+		t.Fatal(err)
+	}
+	dag.Run(0)
+	if got := fmt.Sprint(calls); got != "[outer:a inner:a outer:b inner:b]" {
+		t.Fatal("unexpected calls:", got)
+	}
+	// 中间件在首次运行时包裹一次，之后不再重复包裹，也不可再注册
+	if err := dag.Use(trace("late")); err != MiddlewareAfterRunErr {
+		t.Fatal("unexpected err:", err)
+	}
+	dag.Run(0)
+	if n := wraps.Load(); n != 4 {
+		t.Fatal("unexpected wraps:", n)
+	}
+}
+
+func TestFlaky(t *testing.T) {
+	var calls atomic.Int32
+	stable := &Node[int]{Name: "stable", Processor: func(IRuntimeNode, int) error { return nil }}
+	flaky := &Node[int]{Name: "flaky", Owner: "team-a", Processor: func(IRuntimeNode, int) error {
+		if calls.Add(1)%2 == 0 {
+			return errors.New("boom")
+		}
+		return nil
+	}}
+	dag, err := NewDAG(stable, flaky)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := NewRunArchive(RetentionPolicy{})
+	defer archive.Close()
+	for range 5 {
+		archive.Add(dag.Run(0))
+	}
+	nodes := archive.Flaky(FlakyOptions{MinRuns: 3})
+	if len(nodes) != 1 {
+		t.Fatal("unexpected flaky nodes:", nodes)
+	}
+	if n := nodes[0]; n.Name != "flaky" || n.Owner != "team-a" || n.Fingerprint != dag.Fingerprint() || n.Runs != 5 || n.Failures != 2 || n.Flips != 4 || n.FlakeRate != 1 {
+		t.Fatal("unexpected flaky node:", n)
+	}
+}
+
+func TestPanicHandler(t *testing.T) {
+	dag, err := NewDAG(&Node[int]{Name: "a", Processor: func(IRuntimeNode, int) error { panic("boom") }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handled string
+	result := dag.RunWithOptions(0, RunOptions{PanicHandler: func(node IRuntimeNode, value any, stack []byte) {
+		handled = fmt.Sprint(node.GetName(), ":", value, ":", strings.Contains(string(stack), "TestPanicHandler"))
+	}})
+	if handled != "a:boom:true" {
+		t.Fatal("unexpected handler call:", handled)
+	}
+	var panicErr *PanicErr
+	if r := result.ByName("a"); !errors.As(r.Err, &panicErr) || !strings.Contains(r.Err.Error(), "TestPanicHandler") {
+		t.Fatal("panic err should contain stack:", r.Err)
+	}
+}
+
+func TestExecNode(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	echo, err := NewExecNode[map[string]string]("echo", "sh", "-c", "echo {{.msg}}; echo oops >&2; exit {{.code}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dag, err := NewDAG(echo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := ByNode(dag.Run(map[string]string{"msg": "hello", "code": "0"}), echo)
+	if output, ok := r.Output.(ExecOutput); r.Status != Succeeded || !ok || output.Stdout != "hello\n" || output.Stderr != "oops\n" {
+		t.Fatal("unexpected result:", r.Status, r.Output, r.Err)
+	}
+	r = ByNode(dag.Run(map[string]string{"msg": "hello", "code": "3"}), echo)
+	var execErr *ExecErr
+	if output, ok := r.Output.(ExecOutput); !ok || output.ExitCode != 3 || !errors.As(r.Err, &execErr) || execErr.ExitCode != 3 {
+		t.Fatal("unexpected result:", r.Status, r.Output, r.Err)
+	}
+
+	big, err := NewExecNode[struct{}]("big", "sh", "-c", "head -c 2000000 /dev/zero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bigDag, err := NewDAG(big)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = bigDag.Run(struct{}{}).Results[0]
+	if output, ok := r.Output.(ExecOutput); r.Status != Succeeded || !ok || len(output.Stdout) != execOutputLimit || !output.Truncated {
+		t.Fatal("output should be capped:", r.Status, r.Err)
+	}
+
+	// sh 的子进程 sleep 持有输出管道，只终止 sh 时命令需等待 WaitDelay 才能结束
+	sleep, err := NewExecNode[struct{}]("sleep", "sh", "-c", "sleep 10; echo done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sleep.LocalTimeout = 50 * time.Millisecond
+	processor := sleep.Processor
+	exited := make(chan struct{})
+	sleep.Processor = func(node IRuntimeNode, params struct{}) error {
+		defer close(exited)
+		return processor(node, params)
+	}
+	sleepDag, err := NewDAG(sleep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := sleepDag.Run(struct{}{}).Results[0]; !errors.Is(r.Err, TimeoutErr) {
+		t.Fatal("command should be killed on timeout:", r.Status, r.Err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(2 * time.Second):
+		t.Fatal("process group should be killed on timeout")
+	}
+}
+
+func TestHTTPNode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + " " + string(body)))
+	}))
+	defer server.Close()
+	node, err := NewHTTPNode("create", HTTPRequest[string]{
+		Method: http.MethodPost,
+		URL:    server.URL + "/users/{{.}}",
+		Body:   func(params string) ([]byte, error) { return []byte("body-" + params), nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dag, err := NewDAG(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := ByNode(dag.Run("42"), node)
+	if output, ok := r.Output.(HTTPOutput); r.Status != Succeeded || !ok || output.StatusCode != http.StatusCreated || string(output.Body) != "POST /users/42 body-42" {
+		t.Fatal("unexpected result:", r.Status, r.Output, r.Err)
+	}
+
+	node, err = NewHTTPNode("strict", HTTPRequest[string]{URL: server.URL, Expect: []int{http.StatusOK}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dag, err = NewDAG(node); err != nil {
+		t.Fatal(err)
+	}
+	var statusErr *HTTPStatusErr
+	if r := dag.Run("").Results[0]; !errors.As(r.Err, &statusErr) || statusErr.StatusCode != http.StatusCreated {
+		t.Fatal("unexpected err:", r.Err)
+	}
+
+	node, err = NewHTTPNode("slow", HTTPRequest[string]{URL: server.URL + "/slow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dag, err = NewDAG(node); err != nil {
+		t.Fatal(err)
+	}
+	if r := dag.RunWithOptions("", RunOptions{Timeout: 50 * time.Millisecond}).Results[0]; r.Status == Succeeded {
+		t.Fatal("request should time out:", r.Status, r.Err)
+	}
+}
+
+func TestNodeError(t *testing.T) {
+	dag, err := NewDAG(&Node[int]{
+		Name:         "slow",
+		MaxAttempts:  1,
+		LocalTimeout: 20 * time.Millisecond,
+		Processor: func(node IRuntimeNode, _ int) error {
+			<-node.Context().Done()
+			return node.Context().Err()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dag.Run(0).Err()
+	var nodeErr *NodeError
+	if !errors.As(err, &nodeErr) || nodeErr.NodeName != "slow" || nodeErr.Attempt != 1 || !errors.Is(err, TimeoutErr) {
+		t.Fatal("unexpected err:", err)
+	}
+}
+
+func TestTimeoutDeadline(t *testing.T) {
+	wait := func(node IRuntimeNode, _ int) error {
+		<-node.Context().Done()
+		return nil
+	}
+	local := &Node[int]{Name: "local", LocalTimeout: 20 * time.Millisecond, TotalTimeout: time.Second, Processor: wait}
+	total := &Node[int]{Name: "total", LocalTimeout: time.Second, TotalTimeout: 20 * time.Millisecond, Processor: wait}
+	dag, err := NewDAG(local, total)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run(0)
+	if r := ByNode(result, local); r.TimeoutBy != LocalTimeoutErr || !errors.Is(r.Err, LocalTimeoutErr) || !errors.Is(r.Err, TimeoutErr) || errors.Is(r.Err, TotalTimeoutErr) {
+		t.Fatal("unexpected local timeout:", r.TimeoutBy, r.Err)
+	}
+	if r := ByNode(result, total); r.TimeoutBy != TotalTimeoutErr || !errors.Is(r.Err, TotalTimeoutErr) || !errors.Is(r.Err, TimeoutErr) {
+		t.Fatal("unexpected total timeout:", r.TimeoutBy, r.Err)
+	}
+}
+
+func TestRequiredConfig(t *testing.T) {
+	fetch := &Node[int]{Name: "fetch", Config: map[string]string{"url": "http://example"}, RequiredConfig: []string{"url", "token", "region"}}
+	dag, err := NewDAG(fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var missingErr *MissingConfigErr
+	if err := dag.Validate(); !errors.As(err, &missingErr) || missingErr.NodeName != "fetch" || fmt.Sprint(missingErr.Keys) != "[token region]" {
+		t.Fatal("unexpected err:", err)
+	}
+}
+
+func TestRetryIf(t *testing.T) {
+	permanent := errors.New("invalid input")
+	var attempts atomic.Int32
+	dag, err := NewDAG(&Node[int]{
+		Name:        "a",
+		MaxAttempts: 5,
+		RetryIf:     func(err error) bool { return !errors.Is(err, permanent) },
+		Processor: func(IRuntimeNode, int) error {
+			if attempts.Add(1) < 3 {
+				return errors.New("transient")
+			}
+			return permanent
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := dag.Run(0).Results[0]; r.Status != Failed || r.Attempts != 3 || !errors.Is(r.Err, permanent) {
+		t.Fatal("permanent error should stop retrying:", r.Status, r.Attempts, r.Err)
+	}
+}
+
+func TestPanicScope(t *testing.T) {
+	ok := func(IRuntimeNode, int) error { return nil }
+	a := &Node[int]{Name: "a", Processor: ok}
+	b := &Node[int]{Name: "b", Dependencies: []*Node[int]{a}, Processor: func(IRuntimeNode, int) error { panic("boom") }}
+	c := &Node[int]{Name: "c", Dependencies: []*Node[int]{b}, Processor: ok}
+	d := &Node[int]{Name: "d", Dependencies: []*Node[int]{c}, SkipPolicy: SkipAsSatisfied, Processor: ok}
+	sibling := &Node[int]{Name: "sibling", Dependencies: []*Node[int]{a}, Processor: func(IRuntimeNode, int) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}}
+	dag, err := NewDAG(d, sibling)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.RunWithOptions(0, RunOptions{FailFast: true, PanicScope: PanicScopeBranch})
+	if result.Cause != nil || !result.Partial() || ByNode(result, b).Status != Failed || ByNode(result, sibling).Status != Succeeded {
+		t.Fatal("panic should only fail its branch:", result.Err())
+	}
+	for _, node := range []*Node[int]{c, d} {
+		var branchErr *BranchPanicErr
+		if r := ByNode(result, node); r.Status != Skipped || !errors.As(r.Err, &branchErr) || branchErr.NodeName != "b" {
+			t.Fatal("descendant should be skipped:", r.Name, r.Status, r.Err)
+		}
+	}
+
+	result = dag.RunWithOptions(0, RunOptions{PanicScope: PanicScopeRun})
+	if !errors.Is(result.Cause, ErrFailFast) || result.Partial() {
+		t.Fatal("panic should abort the run:", result.Cause)
+	}
+}
+
+func TestBackoffConstructors(t *testing.T) {
+	if constant := ConstantBackoff(time.Second); constant(1) != time.Second || constant(5) != time.Second {
+		t.Fatal("unexpected constant backoff")
+	}
+	if linear := LinearBackoff(time.Second); linear(1) != time.Second || linear(3) != 3*time.Second {
+		t.Fatal("unexpected linear backoff")
+	}
+	exponential := ExponentialBackoff(100*time.Millisecond, time.Second, 0)
+	var waits []time.Duration
+	for attempt := uint(1); attempt <= 6; attempt++ {
+		waits = append(waits, exponential(attempt))
+	}
+	if got := fmt.Sprint(waits); got != "[100ms 200ms 400ms 800ms 1s 1s]" {
+		t.Fatal("unexpected exponential backoff:", got)
+	}
+	if wait := ExponentialBackoff(time.Second, 0, 0)(200); wait <= 0 {
+		t.Fatal("uncapped backoff should not overflow:", wait)
+	}
+
+	// 抖动应分布在 [0.9s, 1.1s] 内，且均值接近 1s、两侧都有取值
+	jittered := ExponentialBackoff(time.Second, 0, 0.1)
+	var sum time.Duration
+	var below, above int
+	const samples = 2000
+	for range samples {
+		wait := jittered(1)
+		if wait < 900*time.Millisecond || wait > 1100*time.Millisecond {
+			t.Fatal("jitter out of range:", wait)
+		}
+		if wait < time.Second {
+			below++
+		} else if wait > time.Second {
+			above++
+		}
+		sum += wait
+	}
+	if mean := sum / samples; mean < 980*time.Millisecond || mean > 1020*time.Millisecond || below < samples/4 || above < samples/4 {
+		t.Fatal("unexpected jitter distribution:", mean, below, above)
+	}
+	if wait := ExponentialBackoff(time.Second, time.Second, 0.5)(3); wait > time.Second {
+		t.Fatal("jittered backoff should be capped:", wait)
+	}
+}
+
+func TestContinuation(t *testing.T) {
+	sleep := func(node IRuntimeNode, _ struct{}) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	}
+	root := &Node[struct{}]{Name: "root"}
+	child := &Node[struct{}]{Name: "child", Processor: sleep}
+	child.AddDependency(root)
+	root2 := &Node[struct{}]{Name: "root2", Processor: sleep}
+	dag, err := NewDAG(child, root2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pool := range []IPool{nil, NewPool(4)} {
+		begin := time.Now()
+		handle := dag.RunAsyncWithOptions(struct{}{}, RunOptions{Pool: pool})
+		if cost := time.Since(begin); cost > 100*time.Millisecond {
+			t.Fatal("RunAsync should not block on dispatch:", cost)
+		}
+		result := handle.Wait()
+		if err := result.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if cost := time.Since(begin); cost > 350*time.Millisecond {
+			t.Fatal("root2 should run in parallel with child:", cost)
+		}
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	var calls atomic.Int32
+	flaky := &Node[struct{}]{
+		Name: "flaky",
+		Processor: func(node IRuntimeNode, _ struct{}) error {
+			calls.Add(1)
+			return errors.New("boom")
+		},
+		QuarantineAfter: 2,
+	}
+	child := &Node[struct{}]{Name: "child", Processor: func(node IRuntimeNode, _ struct{}) error { return nil }}
+	child.AddWeakDependency(flaky)
+	dag, err := NewDAG(child)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if result := dag.Run(struct{}{}); result.ByName("flaky").Status != Failed {
+			t.Fatal("flaky should fail before quarantine")
+		}
+	}
+	result := dag.Run(struct{}{})
+	if r := result.ByName("flaky"); r.Status != Skipped || !errors.Is(r.Err, QuarantinedErr) || r.Attempts != 0 {
+		t.Fatal("quarantined node should be skipped:", r.Status, r.Err, r.Attempts)
+	}
+	if calls.Load() != 2 || result.ByName("child").Status != Succeeded || result.Err() != nil {
+		t.Fatal("unexpected quarantine result:", calls.Load(), result.Err())
+	}
+	dag.ResetQuarantine("flaky")
+	if dag.Run(struct{}{}); calls.Load() != 3 {
+		t.Fatal("reset node should run again")
+	}
+}
+
+func TestSanitizeRunErr(t *testing.T) {
+	sanitizer := func(err error) error {
+		return Redact(err, func(msg string) string { return strings.ReplaceAll(msg, "secret", "***") })
+	}
+	leak := &Node[struct{}]{
+		Name:      "leak",
+		Processor: func(node IRuntimeNode, _ struct{}) error { return errors.New("token=secret") },
+	}
+	dag, err := NewDAGWithOptions(BuildOptions[struct{}]{NoProgressPolicy: NoProgressFail}, leak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range []RunOptions{{FailFast: true}, {}} {
+		opts.ErrSanitizer = sanitizer
+		result := dag.RunWithOptions(struct{}{}, opts)
+		if msg := result.Err().Error(); strings.Contains(msg, "secret") || !strings.Contains(msg, "***") {
+			t.Fatal("secret leaked:", msg)
+		}
+		if !errors.Is(result.Err(), ErrFailFast) && !errors.Is(result.Err(), ErrNoProgress) {
+			t.Fatal("sanitized cause should keep its type:", result.Cause)
+		}
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errors.New("cancelled with secret"))
+	result := dag.RunWithOptions(struct{}{}, RunOptions{Context: ctx, ErrSanitizer: sanitizer})
+	if msg := result.Err().Error(); strings.Contains(msg, "secret") {
+		t.Fatal("secret leaked from cause:", msg)
+	}
+}
+
+func TestRetryQueueLimit(t *testing.T) {
+	pool := NewPool(1)
+	var attempts atomic.Int32
+	flaky := &Node[struct{}]{
+		Name:            "flaky",
+		MaxAttempts:     2,
+		RetryQueueLimit: 1,
+		Processor: func(node IRuntimeNode, _ struct{}) error {
+			if attempts.Add(1) > 1 {
+				return nil
+			}
+			// 等待其余根节点排队，使重试时协程池过载
+			for pool.QueueLen() < 2 {
+				time.Sleep(time.Millisecond)
+			}
+			return errors.New("first attempt")
+		},
+	}
+	noop := func(node IRuntimeNode, _ struct{}) error { return nil }
+	b := &Node[struct{}]{Name: "b", Processor: noop}
+	c := &Node[struct{}]{Name: "c", Processor: noop}
+	dag, err := NewDAG(flaky, b, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 推迟的重试若占用唯一的 worker，排队的节点永远无法执行
+	result := dag.RunWithOptions(struct{}{}, RunOptions{Pool: pool, Timeout: 2 * time.Second})
+	if err := result.Err(); err != nil || attempts.Load() != 2 {
+		t.Fatal("deferred retry should release the worker:", err, attempts.Load())
+	}
+}
+
+func namedProcessor(IRuntimeNode, int) error {
+	return nil
+}
+
+func TestNameFunc(t *testing.T) {
+	a := &Node[int]{Processor: namedProcessor}
+	b := &Node[int]{Dependencies: []*Node[int]{a}}
+	dag, err := NewDAG(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := dag.Definition().Nodes; got[0].Name != "noname" || got[1].Name != "noname" {
+		t.Fatal("unexpected default names:", got[0].Name, got[1].Name)
+	}
+	dag, err = NewDAGWithOptions(BuildOptions[int]{NameFunc: NameByProcessor}, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b 没有 processor，退化为 NameByIndex
+	if got := dag.Definition().Nodes; got[0].Name != "node-0" || got[1].Name != "easy-dag.namedProcessor" {
+		t.Fatal("unexpected names:", got[0].Name, got[1].Name)
+	}
+}
+
+func TestInheritTimeout(t *testing.T) {
+	var slowEnd, runDDL, begin, ddl time.Time
+	slow := &Node[int]{
+		Name:     "slow",
+		Priority: 2,
+		Processor: func(node IRuntimeNode, params int) error {
+			time.Sleep(100 * time.Millisecond)
+			runDDL, _ = node.Context().Deadline()
+			slowEnd = time.Now()
+			return nil
+		},
+	}
+	hog := func(name string) *Node[int] {
+		return &Node[int]{
+			Name:     name,
+			Priority: 1,
+			Processor: func(node IRuntimeNode, params int) error {
+				time.Sleep(300 * time.Millisecond)
+				return nil
+			},
+		}
+	}
+	fast := &Node[int]{Name: "fast", Priority: 3}
+	inherit := &Node[int]{
+		Name:           "inherit",
+		InheritTimeout: true,
+		Dependencies:   []*Node[int]{fast, slow},
+		Processor: func(node IRuntimeNode, params int) error {
+			begin = time.Now()
+			ddl, _ = node.Context().Deadline()
+			return nil
+		},
+	}
+	leaf := &Node[int]{Name: "leaf", Dependencies: []*Node[int]{inherit}}
+	dag, err := NewDAG(leaf, hog("hog1"), hog("hog2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 设置了超时的节点需要额外的 worker 执行 processor，inherit 排在 hog2 之后
+	opts := RunOptions{Pool: NewPool(2), Timeout: 2 * time.Second, DispatchOrder: DispatchByPriority}
+	if err := dag.RunWithOptions(0, opts).Err(); err != nil {
+		t.Fatal(err)
+	}
+	// inherit 在 hog 之后才开始执行，分得的时间仍按 slow 完成时的剩余运行时间与链长 2 计算
+	want := runDDL.Sub(slowEnd) / 2
+	if got := ddl.Sub(begin); got < want-30*time.Millisecond || got > want+30*time.Millisecond {
+		t.Fatal("unexpected inherited timeout:", got, want)
+	}
+}
+
+func TestDispatchOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	newNode := func(name string, priority int, weight float64) *Node[int] {
+		return &Node[int]{
+			Name:     name,
+			Priority: priority,
+			Weight:   weight,
+			Processor: func(node IRuntimeNode, params int) error {
+				mu.Lock()
+				order = append(order, node.GetName())
+				mu.Unlock()
+				return nil
+			},
+		}
+	}
+	a, b, c := newNode("a", 1, 3), newNode("b", 3, 1), newNode("c", 2, 2)
+	dag, err := NewDAG(a, b, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 单个 worker 按提交顺序执行根节点，懒加载模式使用相同的调度顺序
+	for _, tc := range []struct {
+		opts RunOptions
+		want string
+	}{
+		{RunOptions{}, "[a b c]"},
+		{RunOptions{DispatchOrder: DispatchByPriority}, "[b c a]"},
+		{RunOptions{DispatchOrder: DispatchByPriority, Lazy: true}, "[b c a]"},
+		{RunOptions{DispatchOrder: DispatchByWeight}, "[a c b]"},
+	} {
+		order = nil
+		tc.opts.Pool = NewPool(1)
+		if err := dag.RunWithOptions(0, tc.opts).Err(); err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(order); got != tc.want {
+			t.Fatal("unexpected order:", tc.opts.DispatchOrder, got)
+		}
+	}
+}
+
+func TestEventSink(t *testing.T) {
+	var attempts atomic.Int32
+	flaky := &Node[int]{
+		Name:        "flaky",
+		Owner:       "team-a",
+		MaxAttempts: 2,
+		Processor: func(node IRuntimeNode, params int) error {
+			if attempts.Add(1) == 1 {
+				return errors.New("transient")
+			}
+			return nil
+		},
+	}
+	broken := &Node[int]{
+		Name:         "broken",
+		Dependencies: []*Node[int]{flaky},
+		Processor: func(node IRuntimeNode, params int) error {
+			return errors.New("boom")
+		},
+	}
+	dag, err := NewDAG(broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var events []RunEvent
+	sink := EventSinkFunc(func(event *RunEvent) {
+		mu.Lock()
+		events = append(events, *event)
+		mu.Unlock()
+	})
+	result := dag.RunWithOptions(0, RunOptions{EventSink: sink, Labels: map[string]string{"env": "test"}})
+	var got []string
+	for _, e := range events {
+		got = append(got, string(e.Type)+":"+e.Node)
+		if e.Version != RunEventVersion || e.RunID != result.ID || e.Labels["env"] != "test" {
+			t.Fatal("unexpected event header:", e)
+		}
+	}
+	want := "[run.started: node.started:flaky node.finished:flaky node.started:broken node.finished:broken run.finished:]"
+	if fmt.Sprint(got) != want {
+		t.Fatal("unexpected events:", got)
+	}
+	// 重试后成功的节点只发送一次开始与结束事件
+	if e := events[2]; e.Status != Succeeded || e.Attempts != 2 || e.Owner != "team-a" || e.Error != "" {
+		t.Fatal("unexpected flaky event:", e)
+	}
+	if e := events[4]; e.Status != Failed || e.Attempts != 1 || e.Error != "boom" {
+		t.Fatal("unexpected broken event:", e)
+	}
+	if e := events[5]; e.Cost != result.Cost || e.Error != "" {
+		t.Fatal("unexpected run event:", e)
+	}
+}
+
+func TestRetryBreaker(t *testing.T) {
+	breaker := NewRetryBreaker(1, 50*time.Millisecond)
+	if !breaker.acquire(context.Background()) || !breaker.acquire(context.Background()) || breaker.InFlight() != 2 {
+		t.Fatal("retries below the threshold should not wait")
+	}
+	// 进行中的重试数超过阈值后熔断，冷却期间新的重试需要等待
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if breaker.acquire(ctx) {
+		t.Fatal("breaker should be open")
+	}
+	begin := time.Now()
+	if !breaker.acquire(context.Background()) || time.Since(begin) < 30*time.Millisecond {
+		t.Fatal("breaker should reset after the cooldown:", time.Since(begin))
+	}
+	for range 3 {
+		breaker.release()
+	}
+	if breaker.InFlight() != 0 {
+		t.Fatal("unexpected in-flight retries:", breaker.InFlight())
+	}
+}
+
+func TestMock(t *testing.T) {
+	var calls, hooks atomic.Int32
+	newNode := func(name string) *Node[int] {
+		return &Node[int]{
+			Name: name,
+			Processor: func(node IRuntimeNode, params int) error {
+				calls.Add(1)
+				return errors.New("real backend")
+			},
+			Load: func(node IRuntimeNode, params int) (bool, error) {
+				calls.Add(1)
+				return true, nil
+			},
+			Persist: func(node IRuntimeNode, params int) error {
+				calls.Add(1)
+				return nil
+			},
+			OnSuccess: func(node IRuntimeNode, params int) {
+				hooks.Add(1)
+			},
+		}
+	}
+	fast, slow, timeout := newNode("fast"), newNode("slow"), newNode("timeout")
+	slow.AddDependency(fast)
+	timeout.LocalTimeout = 10 * time.Millisecond
+	dag, err := NewDAG(slow, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &MockOptions{Latency: map[string]time.Duration{"slow": 30 * time.Millisecond, "timeout": time.Second}}
+	result := dag.RunWithOptions(0, RunOptions{Mock: mock})
+	if calls.Load() != 0 || hooks.Load() != 2 {
+		t.Fatal("mock run should skip processors, Load and Persist but keep hooks:", calls.Load(), hooks.Load())
+	}
+	if r := ByNode(result, fast); r.Status != Succeeded {
+		t.Fatal("unexpected fast result:", r.Status, r.Err)
+	}
+	if r := ByNode(result, slow); r.Status != Succeeded || r.Cost < 30*time.Millisecond {
+		t.Fatal("unexpected slow result:", r.Status, r.Cost)
+	}
+	if r := ByNode(result, timeout); !errors.Is(r.Err, TimeoutErr) {
+		t.Fatal("mock latency should respect timeouts:", r.Status, r.Err)
+	}
+}
+
+func TestLazy(t *testing.T) {
+	var ran sync.Map
+	release := make(chan struct{})
+	started := make(chan struct{})
+	newNode := func(name string, deps ...*Node[int]) *Node[int] {
+		return &Node[int]{
+			Name:         name,
+			Dependencies: deps,
+			Processor: func(node IRuntimeNode, params int) error {
+				ran.Store(name, true)
+				return nil
+			},
+		}
+	}
+	gate := &Node[int]{
+		Name: "gate",
+		Processor: func(node IRuntimeNode, params int) error {
+			close(started)
+			<-release
+			return nil
+		},
+	}
+	a := newNode("a", gate)
+	b := newNode("b", a)
+	other := newNode("other")
+	dag, err := NewDAG(b, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 懒加载与目标节点：不是目标祖先的节点不执行
+	result := dag.RunWithOptions(0, RunOptions{Lazy: true, Targets: []string{"other"}})
+	if ByNode(result, other).Status != Succeeded || ByNode(result, b).Status != Skipped {
+		t.Fatal("unexpected targeted result:", ByNode(result, other).Status, ByNode(result, b).Status)
+	}
+	if _, ok := ran.Load("a"); ok {
+		t.Fatal("pruned node should not run")
+	}
+
+	// 懒加载与暂停、恢复：暂停期间就绪的节点在恢复后才被创建并执行
+	handle := dag.RunAsyncWithOptions(0, RunOptions{Lazy: true})
+	<-started
+	handle.Pause()
+	close(release)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := ran.Load("a"); ok {
+		t.Fatal("node dispatched while paused")
+	}
+	handle.Resume()
+	if result := handle.Wait(); result.Err() != nil || ByNode(result, b).Status != Succeeded {
+		t.Fatal("run not resumed:", result.Err())
+	}
+
+	// 懒加载与终止：已创建的运行中节点可被终止，其下游不会被创建
+	stuck := &Node[int]{
+		Name: "stuck",
+		Processor: func(node IRuntimeNode, params int) error {
+			<-node.Context().Done()
+			return node.Context().Err()
+		},
+	}
+	after := newNode("after", stuck)
+	killDag, err := NewDAG(after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle = killDag.RunAsyncWithOptions(0, RunOptions{Lazy: true})
+	if handle.Kill("after") {
+		t.Fatal("node not created yet should not be killed")
+	}
+	for !handle.Kill("stuck") {
+		time.Sleep(time.Millisecond)
+	}
+	result = handle.Wait()
+	if r := ByNode(result, stuck); r.Status != Failed || !errors.Is(r.Err, OperatorKilledErr) {
+		t.Fatal("unexpected killed result:", r.Status, r.Err)
+	}
+	if r := ByNode(result, after); r.Status != Waiting {
+		t.Fatal("downstream of a killed node should not be created:", r.Status)
+	}
+}
+
+func TestResourceLimiter(t *testing.T) {
+	limiter := NewResourceLimiter(map[string]int{"db": 1, "api": 1})
+	var holding, peak atomic.Int32
+	newDag := func(resources ...string) *DAG[int] {
+		node := &Node[int]{
+			Name:      "use",
+			Resources: resources,
+			Processor: func(node IRuntimeNode, params int) error {
+				cur := holding.Add(1)
+				defer holding.Add(-1)
+				for {
+					old := peak.Load()
+					if cur <= old || peak.CompareAndSwap(old, cur) {
+						break
+					}
+				}
+				if limiter.Holding("db") != 1 || limiter.Holding("api") != 1 {
+					return errors.New("resource not held")
+				}
+				time.Sleep(time.Millisecond)
+				return nil
+			},
+		}
+		dag, err := NewDAG(node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dag
+	}
+	// 两个图以相反的顺序声明资源，按排序后的顺序获取，不会相互死锁
+	dags := []*DAG[int]{newDag("db", "api"), newDag("api", "db", "api")}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	var failed atomic.Int32
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := dags[i%2].RunWithOptions(i, RunOptions{Context: ctx, Resources: limiter}).Err(); err != nil {
+				failed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if failed.Load() != 0 || peak.Load() != 1 {
+		t.Fatal("resources should be held by one run at a time:", failed.Load(), peak.Load())
+	}
+	if limiter.Holding("db") != 0 || limiter.Holding("api") != 0 {
+		t.Fatal("resources should be released")
+	}
+}
+
+func TestWorkerHooks(t *testing.T) {
+	pool := NewPool(2)
+	var inits, cleanups, active atomic.Int32
+	pool.SetWorkerHooks(func() {
+		inits.Add(1)
+		active.Add(1)
+	}, func() {
+		active.Add(-1)
+		cleanups.Add(1)
+	})
+	var wg sync.WaitGroup
+	var outside atomic.Int32
+	for range 10 {
+		wg.Add(1)
+		pool.Submit(func() {
+			defer wg.Done()
+			// 任务总是在已初始化的 worker 上执行
+			if active.Load() == 0 {
+				outside.Add(1)
+			}
+			time.Sleep(time.Millisecond)
+		})
+	}
+	wg.Wait()
+	for cleanups.Load() != inits.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	if n := inits.Load(); n < 1 || n > 2 || outside.Load() != 0 {
+		t.Fatal("unexpected worker hooks:", n, outside.Load())
+	}
+}
+
+func TestNodeStatusText(t *testing.T) {
+	for _, status := range []NodeStatus{Waiting, Running, Succeeded, Failed, Cancelled, Skipped} {
+		data, err := json.Marshal(status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != `"`+status.String()+`"` {
+			t.Fatal("unexpected json:", string(data))
+		}
+		var decoded NodeStatus
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded != status {
+			t.Fatal("round trip failed:", status, decoded, err)
+		}
+	}
+	if NodeStatus(42).String() != "unknown" {
+		t.Fatal("unexpected name for an unknown status")
+	}
+	var decoded NodeStatus
+	if err := json.Unmarshal([]byte(`"done"`), &decoded); err == nil {
+		t.Fatal("unknown status name should be rejected")
+	}
+}
+
+func TestRemainingBudget(t *testing.T) {
+	budgets := make([]time.Duration, 4)
+	newNode := func(i int, local, total time.Duration) *Node[int] {
+		return &Node[int]{
+			Name:         strconv.Itoa(i),
+			LocalTimeout: local,
+			TotalTimeout: total,
+			Processor: func(node IRuntimeNode, params int) error {
+				budgets[i] = node.RemainingBudget()
+				return nil
+			},
+		}
+	}
+	dag, err := NewDAG(newNode(0, 0, 0), newNode(1, time.Second, 0), newNode(2, time.Second, 500*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dag.Run(0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if budgets[0] != math.MaxInt64 {
+		t.Fatal("node without deadlines should have an unlimited budget:", budgets[0])
+	}
+	// 取本地超时与全局超时中较早的截止时间
+	if budgets[1] <= 900*time.Millisecond || budgets[1] > time.Second {
+		t.Fatal("unexpected local budget:", budgets[1])
+	}
+	if budgets[2] <= 400*time.Millisecond || budgets[2] > 500*time.Millisecond {
+		t.Fatal("unexpected total budget:", budgets[2])
+	}
+	// 运行的整体超时同样限制节点的剩余时间
+	runDag, err := NewDAG(newNode(3, time.Second, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runDag.RunWithOptions(0, RunOptions{Timeout: 300 * time.Millisecond}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if budgets[3] <= 200*time.Millisecond || budgets[3] > 300*time.Millisecond {
+		t.Fatal("unexpected run budget:", budgets[3])
+	}
+}
+
+func TestCodec(t *testing.T) {
+	type point struct{ X, Y int }
+	for _, codec := range []Codec{JSONCodec{}, GobCodec{}} {
+		data, err := EncodeOutput(codec, &NodeResult{Output: point{1, 2}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, err := DecodeOutput[point](codec, data); err != nil || p != (point{1, 2}) {
+			t.Fatalf("%T round trip: %v %v", codec, p, err)
+		}
+		if data, err := EncodeOutput(codec, &NodeResult{}); data != nil || err != nil {
+			t.Fatalf("%T should skip empty output: %v %v", codec, data, err)
+		}
+	}
+	// 检查点按 RunOptions.Codec 序列化，恢复时下游通过 InputOf 按类型反序列化
+	fail := true
+	load := NewDataNode("load", func(node IRuntimeNode, params int) (point, error) {
+		return point{params, params * 2}, nil
+	})
+	save := NewDataNode("save", func(node IRuntimeNode, params int) (int, error) {
+		if fail {
+			return 0, errors.New("crashed")
+		}
+		p, ok := InputOf[point](node, "load")
+		if !ok {
+			return 0, errors.New("missing input")
+		}
+		return p.X + p.Y, nil
+	})
+	save.AddDependency(load)
+	dag, err := NewDAG(save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := RunOptions{RunID: "job", Checkpoint: NewMemoryCheckpointStore(), Codec: GobCodec{}}
+	if result := dag.RunWithOptions(1, opts); result.Err() == nil || result.CheckpointErr != nil {
+		t.Fatal("expected failure:", result.CheckpointErr)
+	}
+	fail = false
+	result, err := dag.Resume("job", 1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := ByNode(result, save); r.Status != Succeeded || r.Output != 3 {
+		t.Fatal("unexpected resume:", r.Status, r.Output, r.Err)
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	broken := &Node[int]{
+		Name:         "broken",
+		Owner:        "team-a",
+		AlertChannel: "#team-a-oncall",
+		Processor: func(node IRuntimeNode, params int) error {
+			return errors.New("boom")
+		},
+	}
+	after := &Node[int]{Name: "after", Owner: "team-b", Dependencies: []*Node[int]{broken}}
+	dag, err := NewDAG(after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var alerts []string
+	sink := EventSinkFunc(func(event *RunEvent) {
+		if event.Type == EventNodeFinished && event.Status == Failed {
+			mu.Lock()
+			alerts = append(alerts, event.Owner+" "+event.AlertChannel)
+			mu.Unlock()
+		}
+	})
+	result := dag.RunWithOptions(0, RunOptions{EventSink: sink})
+	if fmt.Sprint(alerts) != "[team-a #team-a-oncall]" {
+		t.Fatal("unexpected alerts:", alerts)
+	}
+	if r := ByNode(result, broken); r.Owner != "team-a" || r.AlertChannel != "#team-a-oncall" {
+		t.Fatal("unexpected broken result:", r.Owner, r.AlertChannel)
+	}
+	// 未执行的节点同样携带负责人
+	if r := ByNode(result, after); r.Status == Succeeded || r.Owner != "team-b" || r.AlertChannel != "" {
+		t.Fatal("unexpected after result:", r.Status, r.Owner, r.AlertChannel)
+	}
+	def := dag.Definition()
+	if n := def.Nodes[1]; n.Owner != "team-a" || n.AlertChannel != "#team-a-oncall" {
+		t.Fatal("unexpected definition:", n)
+	}
+}
+
+func TestRunResult(t *testing.T) {
+	node1 := &Node[int]{Name: "node1", Processor: func(IRuntimeNode, int) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}}
+	node2 := &Node[int]{Name: "node2", Dependencies: []*Node[int]{node1}, Processor: func(IRuntimeNode, int) error { return nil }}
+	dag, err := NewDAG(node1, node2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := NewPool(2)
+	labels := map[string]string{"env": "test"}
+	result := dag.RunWithOptions(0, RunOptions{Pool: pool, Labels: labels})
+	if result.Pool != pool || result.Labels["env"] != "test" || result.ID == "" || result.Fingerprint != dag.Fingerprint() {
+		t.Fatal("unexpected run record:", result.Pool, result.Labels, result.ID)
+	}
+	if result.End.Sub(result.Begin) != result.Cost || result.Cost < 10*time.Millisecond {
+		t.Fatal("unexpected run time:", result.Begin, result.End, result.Cost)
+	}
+	if len(result.Results) != 2 || result.Results[0] != ByNode(result, node1) || result.Results[1].Name != "node2" {
+		t.Fatal("results should follow node order:", result.Results)
+	}
+	if r := result.Results[1]; r.Begin.Before(result.Begin) || r.Begin.Add(r.Cost).After(result.End) {
+		t.Fatal("node should run within the run:", r.Begin, r.Cost)
+	}
+	if result := dag.Run(0); result.Pool != nil || result.Labels != nil {
+		t.Fatal("run without pool or labels:", result.Pool, result.Labels)
+	}
+}
+
+func TestAnalyzeBottlenecks(t *testing.T) {
+	sleep := func(name string, d time.Duration) *Node[int] {
+		return &Node[int]{Name: name, Processor: func(IRuntimeNode, int) error {
+			time.Sleep(d)
+			return nil
+		}}
+	}
+	var attempts atomic.Int32
+	a := &Node[int]{Name: "a", MaxAttempts: 2, Processor: func(IRuntimeNode, int) error {
+		time.Sleep(20 * time.Millisecond)
+		if attempts.Add(1) == 1 {
+			return errors.New("transient")
+		}
+		return nil
+	}}
+	b := sleep("b", 60*time.Millisecond)
+	b.AddDependency(a)
+	c := sleep("c", 5*time.Millisecond)
+	dag, err := NewDAG(b, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := dag.AnalyzeBottlenecks(dag.Run(0))
+	if fmt.Sprint(report.CriticalPath) != "[a b]" {
+		t.Fatal("unexpected critical path:", report.CriticalPath)
+	}
+	if item := report.Items[0]; item.Name != "b" || !item.OnCriticalPath || item.CriticalShare <= 0.5 {
+		t.Fatal("b should rank first:", item)
+	}
+	if item := report.Items[1]; item.Name != "a" || item.Retries != 1 {
+		t.Fatal("unexpected a:", item)
+	}
+	if item := report.Items[2]; item.Name != "c" || item.OnCriticalPath || item.CriticalShare != 0 {
+		t.Fatal("unexpected c:", item)
+	}
+	if str := report.String(); !strings.Contains(str, "critical path: a -> b\n") {
+		t.Fatal("unexpected report:", str)
+	}
+	// 其他图的运行结果与 nil 不可分析
+	other, err := NewDAG(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dag.AnalyzeBottlenecks(other.Run(0)) != nil || dag.AnalyzeBottlenecks(nil) != nil {
+		t.Fatal("mismatched result should not be analyzed")
+	}
+}
+
+func TestOnFinish(t *testing.T) {
+	var mu sync.Mutex
+	snapshots := make(map[string]*NodeResult)
+	onFinish := func(node IRuntimeNode, params int, result *NodeResult) {
+		mu.Lock()
+		snapshots[result.Name] = result
+		mu.Unlock()
+		// 修改快照不影响运行结果
+		result.Status = Waiting
+	}
+	ok := &Node[int]{Name: "ok", OnFinish: onFinish, Processor: func(node IRuntimeNode, params int) error {
+		node.SetOutput(params + 1)
+		return nil
+	}}
+	var calls atomic.Int32
+	broken := &Node[int]{Name: "broken", MaxAttempts: 2, OnFinish: onFinish, Processor: func(IRuntimeNode, int) error {
+		calls.Add(1)
+		return errors.New("boom")
+	}}
+	dag, err := NewDAG(ok, broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run(1)
+	if len(snapshots) != 2 || calls.Load() != 2 {
+		t.Fatal("OnFinish should be called once per node:", len(snapshots), calls.Load())
+	}
+	if r := snapshots["ok"]; r.Output != 2 || r.Err != nil || r.Attempts != 1 || r.Begin.IsZero() {
+		t.Fatal("unexpected ok snapshot:", r)
+	}
+	if r := snapshots["broken"]; r.Err == nil || r.Err.Error() != "boom" || r.Attempts != 2 {
+		t.Fatal("unexpected broken snapshot:", r)
+	}
+	if ByNode(result, ok).Status != Succeeded || ByNode(result, broken).Status != Failed {
+		t.Fatal("snapshot should be a copy:", ByNode(result, ok).Status, ByNode(result, broken).Status)
+	}
+}
+
+func TestWriteAsCSV(t *testing.T) {
+	ok := &Node[int]{Name: "ok", Processor: func(IRuntimeNode, int) error { return nil }}
+	broken := &Node[int]{Name: "broken", Processor: func(IRuntimeNode, int) error {
+		return errors.New("boom, again")
+	}}
+	dag, err := NewDAG(ok, broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.RunWithOptions(0, RunOptions{RunID: "run-1"})
+	var buf bytes.Buffer
+	if err := result.WriteAsCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != "run_id,node,status,begin,cost_ms,attempts,error" {
+		t.Fatal("unexpected rows:", rows)
+	}
+	if row := rows[1]; row[0] != "run-1" || row[1] != "ok" || row[2] != Succeeded.String() || row[5] != "1" || row[6] != "" {
+		t.Fatal("unexpected ok row:", row)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, rows[1][3]); err != nil {
+		t.Fatal("unexpected begin:", err)
+	}
+	// 含逗号的错误信息按 CSV 规则转义
+	if row := rows[2]; row[1] != "broken" || row[2] != Failed.String() || row[6] != "boom, again" {
+		t.Fatal("unexpected broken row:", row)
+	}
+}
+
+func TestCancelCause(t *testing.T) {
+	shutdown := errors.New("shutdown")
+	seen := make(chan error, 1)
+	started := make(chan struct{})
+	running := &Node[int]{Name: "running", Processor: func(node IRuntimeNode, params int) error {
+		close(started)
+		<-node.Context().Done()
+		seen <- context.Cause(node.Context())
+		return nil
+	}}
+	after := &Node[int]{Name: "after", Dependencies: []*Node[int]{running}}
+	dag, err := NewDAG(after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	go func() {
+		<-started
+		cancel(shutdown)
+	}()
+	result := dag.RunContext(ctx, 0)
+	if !errors.Is(result.Cause, shutdown) || !errors.Is(<-seen, shutdown) {
+		t.Fatal("external cause should reach the run and processors:", result.Cause)
+	}
+	if r := ByNode(result, after); r.Status != Cancelled || !errors.Is(r.Err, shutdown) {
+		t.Fatal("unexpected after:", r.Status, r.Err)
+	}
+
+	// 快速失败时终止原因记录失败的节点
+	broken := &Node[int]{Name: "broken", Processor: func(IRuntimeNode, int) error {
+		<-started
+		return errors.New("boom")
+	}}
+	started = make(chan struct{})
+	dag, err = NewDAG(broken, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = dag.RunWithOptions(0, RunOptions{FailFast: true})
+	var failFast *FailFastErr
+	if !errors.As(result.Cause, &failFast) || failFast.NodeName != "broken" || failFast.Err.Error() != "boom" {
+		t.Fatal("unexpected fail fast cause:", result.Cause)
+	}
+	if err := <-seen; !errors.As(err, &failFast) || failFast.NodeName != "broken" {
+		t.Fatal("processor should see the fail fast cause:", err)
+	}
+}
+
+func TestRunHandleDone(t *testing.T) {
+	release := make(chan struct{})
+	node := &Node[int]{Name: "node", Processor: func(IRuntimeNode, int) error {
+		<-release
+		return nil
+	}}
+	dag, err := NewDAG(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle := dag.RunAsync(0)
+	select {
+	case <-handle.Done():
+		t.Fatal("run finished before node released")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-handle.Done():
+	case <-time.After(time.Second):
+		t.Fatal("run not finished")
+	}
+	// 运行结束后取消无影响，Wait 可重复调用
+	handle.Cancel()
+	if result := handle.Wait(); result.Cause != nil || result != handle.Wait() || result.Results[0].Status != Succeeded {
+		t.Fatal("unexpected result:", result.Cause)
+	}
+}
+
+func TestSubRuns(t *testing.T) {
+	sub, err := NewDAG(&Node[int]{Name: "inner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 在 processor 内多次运行子图，每次运行都挂载到父节点
+	parent := &Node[int]{Name: "parent", Processor: func(node IRuntimeNode, params int) error {
+		for i := range 2 {
+			if err := sub.RunWithOptions(params, RunOptions{RunID: strconv.Itoa(i), Parent: node}).Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+	dag, err := NewDAG(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.RunWithOptions(0, RunOptions{RunID: "root"})
+	subRuns := ByNode(result, parent).SubRuns
+	if len(subRuns) != 2 {
+		t.Fatal("unexpected sub runs:", len(subRuns))
+	}
+	for i, run := range subRuns {
+		if run.ParentID != "root" || run.ID != "root/"+strconv.Itoa(i) || run.Results[0].Status != Succeeded {
+			t.Fatal("unexpected sub run:", run.ParentID, run.ID)
+		}
+	}
+}
+
+// peakCounter 记录同时执行的最大数量
+type peakCounter struct {
+	cur, peak atomic.Int32
+}
+
+func (c *peakCounter) processor(d time.Duration) func(IRuntimeNode, int) error {
+	return func(IRuntimeNode, int) error {
+		cur := c.cur.Add(1)
+		for {
+			peak := c.peak.Load()
+			if cur <= peak || c.peak.CompareAndSwap(peak, cur) {
+				break
+			}
+		}
+		time.Sleep(d)
+		c.cur.Add(-1)
+		return nil
+	}
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	var counter peakCounter
+	dag, err := NewDAG(&Node[int]{Name: "limited", MaxConcurrent: 1, Processor: counter.processor(10 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handles []*RunHandle
+	for i := range 4 {
+		handles = append(handles, dag.RunAsync(i))
+	}
+	for _, handle := range handles {
+		if err := handle.Wait().Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counter.peak.Load() != 1 {
+		t.Fatal("node should not run concurrently across runs:", counter.peak.Load())
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	var counter peakCounter
+	limiter := NewLimiter(2)
+	newDAG := func(prefix string) *DAG[int] {
+		var nodes []*Node[int]
+		for i := range 3 {
+			nodes = append(nodes, &Node[int]{Name: prefix + strconv.Itoa(i), Processor: counter.processor(10 * time.Millisecond)})
+		}
+		dag, err := NewDAG(nodes...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dag
+	}
+	// 不同的图共享同一个限制器
+	handles := []*RunHandle{
+		newDAG("a").RunAsyncWithOptions(0, RunOptions{Limiter: limiter}),
+		newDAG("b").RunAsyncWithOptions(0, RunOptions{Limiter: limiter}),
+	}
+	for _, handle := range handles {
+		if err := handle.Wait().Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if peak := counter.peak.Load(); peak != 2 || limiter.Running() != 0 {
+		t.Fatal("unexpected concurrency:", peak, limiter.Running())
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	load := &Node[int]{Name: "load", LocalTimeout: time.Second, MaxAttempts: 3, Config: map[string]string{"table": "users"}}
+	enrich := &Node[int]{Name: "enrich"}
+	save := &Node[int]{
+		Name:             "save",
+		TotalTimeout:     2 * time.Second,
+		MaxConcurrent:    2,
+		Tags:             []string{"io"},
+		Dependencies:     []*Node[int]{load},
+		WeakDependencies: []*Node[int]{enrich},
+	}
+	dag, err := NewDAG(save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := dag.Definition()
+	if len(def.Nodes) != 3 {
+		t.Fatal("unexpected nodes:", def.Nodes)
+	}
+	if n := def.Nodes[0]; n.Name != "save" || n.TotalTimeout != 2*time.Second || n.MaxConcurrent != 2 ||
+		fmt.Sprint(n.Dependencies, n.WeakDependencies, n.Tags) != "[load] [enrich] [io]" {
+		t.Fatal("unexpected save:", n)
+	}
+	if n := def.Nodes[1]; n.Name != "load" || n.LocalTimeout != time.Second || n.MaxAttempts != 3 || n.Config["table"] != "users" {
+		t.Fatal("unexpected load:", n)
+	}
+	// 快照与用户节点、其他快照互不影响
+	load.Config["table"] = "orders"
+	save.Tags[0] = "cpu"
+	def.Nodes[1].Config["table"] = "events"
+	if n := dag.Definition().Nodes; n[1].Config["table"] != "users" || n[0].Tags[0] != "io" {
+		t.Fatal("definition should be a deep copy:", n[1].Config, n[0].Tags)
+	}
+}
+
+func TestCriticalPath(t *testing.T) {
+	newNode := func(name string, weight float64, deps ...*Node[int]) *Node[int] {
+		return &Node[int]{Name: name, Weight: weight, Dependencies: deps}
+	}
+	// a(1) -> b(5) -> d(1)，a(1) -> c(2) -> d(1)，e(3) 独立
+	a := newNode("a", 1)
+	b := newNode("b", 5, a)
+	c := newNode("c", 2, a)
+	d := newNode("d", 1, b, c)
+	e := newNode("e", 3)
+	dag, err := NewDAG(d, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path, weight := dag.CriticalPath(); fmt.Sprint(path) != "[a b d]" || weight != 7 {
+		t.Fatal("unexpected critical path:", path, weight)
+	}
+	// 调整权重后关键路径随之变化
+	c.Weight = 6
+	if dag, err = NewDAG(d, e); err != nil {
+		t.Fatal(err)
+	}
+	if path, weight := dag.CriticalPath(); fmt.Sprint(path) != "[a c d]" || weight != 8 {
+		t.Fatal("unexpected critical path:", path, weight)
+	}
+	e.Weight = 10
+	if dag, err = NewDAG(d, e); err != nil {
+		t.Fatal(err)
+	}
+	if path := dag.AnalyzeBottlenecks(dag.Run(0)).PlannedCriticalPath; fmt.Sprint(path) != "[e]" {
+		t.Fatal("unexpected planned critical path:", path)
+	}
+}
+
+func TestLoadPersist(t *testing.T) {
+	var store sync.Map
+	var calls atomic.Int32
+	var loadErr, persistErr error
+	node := &Node[*int]{
+		Name: "square",
+		Load: func(node IRuntimeNode, params *int) (bool, error) {
+			if loadErr != nil {
+				return false, loadErr
+			}
+			v, ok := store.Load(*params)
+			if ok {
+				*params = v.(int)
+			}
+			return ok, nil
+		},
+		Processor: func(node IRuntimeNode, params *int) error {
+			calls.Add(1)
+			*params *= *params
+			return nil
+		},
+		Persist: func(node IRuntimeNode, params *int) error {
+			if persistErr != nil {
+				return persistErr
+			}
+			store.Store(int(math.Sqrt(float64(*params))), *params)
+			return nil
+		},
+	}
+	dag, err := NewDAG(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(n int) (int, *NodeResult) {
+		result := dag.Run(&n)
+		return n, result.Results[0]
+	}
+	if n, r := run(3); n != 9 || r.Status != Succeeded || calls.Load() != 1 {
+		t.Fatal("miss should execute:", n, r.Status, calls.Load())
+	}
+	if n, r := run(3); n != 9 || r.Status != Succeeded || calls.Load() != 1 {
+		t.Fatal("hit should skip execution:", n, r.Status, calls.Load())
+	}
+	// 加载失败视为未命中，保存失败不影响节点状态
+	loadErr, persistErr = errors.New("cache down"), errors.New("disk full")
+	if n, r := run(3); n != 9 || r.Status != Succeeded || calls.Load() != 2 || r.PersistErr != persistErr {
+		t.Fatal("unexpected degraded cache run:", n, r.Status, calls.Load(), r.PersistErr)
+	}
+}
+
+func TestConcurrencyGauge(t *testing.T) {
+	sleep := func(IRuntimeNode, int) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}
+	a, b, c := &Node[int]{Name: "a", Processor: sleep}, &Node[int]{Name: "b", Processor: sleep}, &Node[int]{Name: "c", Processor: sleep}
+	join := &Node[int]{Name: "join", Dependencies: []*Node[int]{a, b, c}, Processor: sleep}
+	dag, err := NewDAG(join)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run(0)
+	if result.PeakConcurrency != 3 || !result.Sampled {
+		t.Fatal("unexpected peak concurrency:", result.PeakConcurrency)
+	}
+	// 每个 processor 开始与结束时各采样一次
+	samples := result.Concurrency
+	if len(samples) != 8 || samples[0].Running != 1 || samples[len(samples)-1].Running != 0 {
+		t.Fatal("unexpected samples:", samples)
+	}
+	if avg := result.AvgConcurrency(); avg <= 1 || avg >= 3 {
+		t.Fatal("unexpected average concurrency:", avg)
+	}
+}
+
+func TestNoProgress(t *testing.T) {
+	var failB atomic.Bool
+	failB.Store(true)
+	a := &Node[int]{Name: "a", Processor: func(IRuntimeNode, int) error { return errors.New("a down") }}
+	b := &Node[int]{Name: "b", Processor: func(IRuntimeNode, int) error {
+		if failB.Load() {
+			return errors.New("b down")
+		}
+		return nil
+	}}
+	fallback := &Node[int]{Name: "fallback", WeakDependencies: []*Node[int]{a, b}, Processor: func(IRuntimeNode, int) error { return nil }}
+	dag, err := NewDAG(fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 默认继续调度弱依赖子节点
+	if result := dag.Run(0); result.Cause != nil || ByNode(result, fallback).Status != Succeeded {
+		t.Fatal("default policy should continue:", result.Cause)
+	}
+	dag, err = NewDAGWithOptions(BuildOptions[int]{NoProgressPolicy: NoProgressFail}, fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run(0)
+	var noProgress *NoProgressErr
+	if !errors.As(result.Err(), &noProgress) || !errors.Is(result.Err(), ErrNoProgress) {
+		t.Fatal("unexpected err:", result.Err())
+	}
+	slices.Sort(noProgress.Roots)
+	if fmt.Sprint(noProgress.Roots) != "[a b]" || len(noProgress.Errs) != 2 || ByNode(result, fallback).Status != Cancelled {
+		t.Fatal("unexpected no progress:", noProgress.Roots, noProgress.Errs, ByNode(result, fallback).Status)
+	}
+	// 仍有根节点成功时不终止
+	failB.Store(false)
+	if result := dag.Run(0); result.Cause != nil || ByNode(result, fallback).Status != Succeeded {
+		t.Fatal("partial root failure should continue:", result.Cause)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	network := &Node[*[]string]{Name: "network"}
+	db := &Node[*[]string]{Name: "db", Dependencies: []*Node[*[]string]{network}}
+	cache := &Node[*[]string]{Name: "cache", WeakDependencies: []*Node[*[]string]{network}}
+	app := &Node[*[]string]{Name: "app", Dependencies: []*Node[*[]string]{db}, WeakDependencies: []*Node[*[]string]{cache}}
+	dag, err := NewDAG(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := dag.ReverseOrder()
+	pos := make(map[string]int, len(order))
+	for i, name := range order {
+		pos[name] = i
+	}
+	if len(order) != 4 || pos["app"] > pos["db"] || pos["db"] > pos["network"] || pos["cache"] > pos["network"] {
+		t.Fatal("unexpected reverse order:", order)
+	}
+	var mu sync.Mutex
+	teardown, err := dag.Reverse(func(def NodeDefinition) *Node[*[]string] {
+		return &Node[*[]string]{Name: def.Name, Processor: func(node IRuntimeNode, params *[]string) error {
+			mu.Lock()
+			*params = append(*params, def.Name)
+			mu.Unlock()
+			return nil
+		}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stopped []string
+	if err := teardown.Run(&stopped).Err(); err != nil || len(stopped) != 4 || stopped[0] != "app" || stopped[3] != "network" {
+		t.Fatal("unexpected teardown:", err, stopped)
+	}
+	// 弱依赖映射为反向的弱依赖
+	def := teardown.Definition()
+	for _, n := range def.Nodes {
+		if n.Name == "cache" && fmt.Sprint(n.Dependencies, n.WeakDependencies) != "[] [app]" {
+			t.Fatal("unexpected cache edges:", n.Dependencies, n.WeakDependencies)
+		}
+	}
+}
+
+func TestNodeConfig(t *testing.T) {
+	var mu sync.Mutex
+	got := make(map[string]string)
+	// 同一个 processor 通过节点配置区分行为
+	fetch := func(node IRuntimeNode, params int) error {
+		mu.Lock()
+		got[node.Config()["region"]] = node.Config()["url"]
+		mu.Unlock()
+		return nil
+	}
+	config := map[string]string{"region": "us", "url": "http://us.example"}
+	us := &Node[int]{Name: "us", Config: config, Processor: fetch}
+	eu := &Node[int]{Name: "eu", Config: map[string]string{"region": "eu", "url": "http://eu.example"}, Processor: fetch}
+	none := &Node[int]{Name: "none", Processor: func(node IRuntimeNode, params int) error {
+		if node.Config() != nil {
+			return errors.New("unexpected config")
+		}
+		return nil
+	}}
+	dag, err := NewDAG(us, eu, none)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 构建后修改用户节点的配置不影响图
+	config["url"] = "http://changed.example"
+	if err := dag.Run(0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got["us"] != "http://us.example" || got["eu"] != "http://eu.example" {
+		t.Fatal("unexpected config:", got)
+	}
+}
+
+func TestContainer(t *testing.T) {
+	type client struct{ addr string }
+	container := NewContainer()
+	Provide(container, &client{addr: "db:5432"})
+	container.Set("region", "us")
+	dag, err := NewDAG(&Node[int]{Name: "query", Processor: func(node IRuntimeNode, params int) error {
+		c, ok := Resolve[*client](node)
+		if !ok || c.addr != "db:5432" {
+			return errors.New("client not resolved")
+		}
+		if region, ok := ResolveKey[string](node, "region"); !ok || region != "us" {
+			return errors.New("region not resolved")
+		}
+		if _, ok := ResolveKey[int](node, "region"); ok {
+			return errors.New("mismatched type should not resolve")
+		}
+		if _, ok := Resolve[client](node); ok {
+			return errors.New("unregistered type should not resolve")
+		}
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dag.RunWithOptions(0, RunOptions{Container: container}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	// 未设置容器时获取不到对象
+	if err := dag.Run(0).Err(); err == nil {
+		t.Fatal("resolve without container should fail")
+	}
+}
+
+func TestResultSink(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]*NodeResult)
+	var runIDs []string
+	sink := ResultSinkFunc(func(runID string, result *NodeResult) {
+		mu.Lock()
+		received[result.Name] = result
+		runIDs = append(runIDs, runID)
+		mu.Unlock()
+	})
+	a := &Node[int]{Name: "a", Processor: func(node IRuntimeNode, params int) error {
+		node.SetOutput(params)
+		return nil
+	}}
+	// 下游节点执行时上游的结果已被接收，无需等待运行结束
+	b := &Node[int]{Name: "b", Dependencies: []*Node[int]{a}, Processor: func(IRuntimeNode, int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if r := received["a"]; r == nil || r.Status != Succeeded || r.Output != 7 {
+			return errors.New("a not streamed")
+		}
+		return nil
+	}}
+	c := &Node[int]{Name: "c", Processor: func(IRuntimeNode, int) error { return errors.New("boom") }}
+	dag, err := NewDAG(b, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.RunWithOptions(7, RunOptions{ResultSink: sink})
+	if ByNode(result, b).Status != Succeeded {
+		t.Fatal("unexpected b:", ByNode(result, b).Err)
+	}
+	if len(received) != 3 || received["c"].Status != Failed || received["c"].Err.Error() != "boom" {
+		t.Fatal("unexpected results:", received)
+	}
+	for _, runID := range runIDs {
+		if runID != result.ID {
+			t.Fatal("unexpected run ID:", runID)
+		}
+	}
+}
+
+func TestExecutionID(t *testing.T) {
+	var ids []string
+	dag, err := NewDAG(&Node[int]{Name: "call", MaxAttempts: 3, Processor: func(node IRuntimeNode, params int) error {
+		ids = append(ids, node.ExecutionID())
+		if len(ids) < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dag.RunWithOptions(0, RunOptions{RunID: "run"}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	// 每次重试的标识不同
+	if fmt.Sprint(ids) != "[run/call/1 run/call/2 run/call/3]" {
+		t.Fatal("unexpected execution IDs:", ids)
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	dag, err := NewDAG(&Node[int]{
+		Name:         "slow",
+		LocalTimeout: 20 * time.Millisecond,
+		MaxAttempts:  2,
+		Processor: func(node IRuntimeNode, _ int) error {
+			// 第一次失败后重试，第二次超时
+			if node.GetAttempts() == 1 {
+				return errors.New("transient")
+			}
+			<-node.Context().Done()
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := dag.Run(0).Results[0]
+	var timeoutErr *TimeoutError
+	if !errors.As(r.Err, &timeoutErr) || !errors.Is(r.Err, TimeoutErr) {
+		t.Fatal("unexpected err:", r.Err)
+	}
+	if timeoutErr.NodeName != "slow" || timeoutErr.Deadline != LocalTimeoutErr || timeoutErr.Timeout != 20*time.Millisecond ||
+		timeoutErr.Elapsed < 20*time.Millisecond || timeoutErr.Attempt != 2 {
+		t.Fatal("unexpected timeout detail:", timeoutErr)
+	}
+	if msg := timeoutErr.Error(); !strings.Contains(msg, "node slow") || !strings.Contains(msg, "attempt 2") {
+		t.Fatal("unexpected message:", msg)
+	}
+}
+
+func TestDegraded(t *testing.T) {
+	ok := func(IRuntimeNode, int) error { return nil }
+	enrich := &Node[int]{Name: "enrich", Processor: func(IRuntimeNode, int) error { return errors.New("enrich down") }}
+	healthy := &Node[int]{Name: "healthy", Processor: ok}
+	// 两个节点在弱依赖失败的情况下运行，另一个节点的弱依赖均成功
+	render := &Node[int]{Name: "render", WeakDependencies: []*Node[int]{enrich, healthy}, Processor: ok}
+	summary := &Node[int]{Name: "summary", WeakDependencies: []*Node[int]{enrich}, Processor: ok}
+	full := &Node[int]{Name: "full", WeakDependencies: []*Node[int]{healthy}, Processor: ok}
+	dag, err := NewDAG(render, summary, full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run(0)
+	if result.Degraded != 2 {
+		t.Fatal("unexpected degraded count:", result.Degraded)
+	}
+	if !ByNode(result, render).Degraded || !ByNode(result, summary).Degraded || ByNode(result, full).Degraded || ByNode(result, enrich).Degraded {
+		t.Fatal("unexpected degraded nodes")
+	}
+	if r := ByNode(result, render); r.Status != Succeeded {
+		t.Fatal("degraded node should still run:", r.Status)
+	}
+}
+
+func TestAwaitDep(t *testing.T) {
+	release := make(chan struct{})
+	slow := NewDataNode("slow", func(IRuntimeNode, int) (int, error) {
+		<-release
+		return 42, nil
+	})
+	fast := NewDataNode("fast", func(IRuntimeNode, int) (int, error) { return 1, nil })
+	var shortOK, longOK, unknownOK bool
+	var status NodeStatus
+	var input int
+	merge := &Node[int]{
+		Name:             "merge",
+		Dependencies:     []*Node[int]{fast},
+		WeakDependencies: []*Node[int]{slow},
+		EarlyStart:       true,
+		Processor: func(node IRuntimeNode, _ int) error {
+			// 弱依赖仍在运行时节点已开始执行
+			_, shortOK = node.AwaitDep("slow", 10*time.Millisecond)
+			_, unknownOK = node.AwaitDep("missing", time.Second)
+			close(release)
+			status, longOK = node.AwaitDep("slow", time.Second)
+			input, _ = InputOf[int](node, "slow")
+			return nil
+		},
+	}
+	dag, err := NewDAG(merge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dag.Run(0)
+	if result.Err() != nil {
+		t.Fatal(result.Err())
+	}
+	if shortOK || unknownOK || !longOK || status != Succeeded || input != 42 {
+		t.Fatal("unexpected await:", shortOK, unknownOK, longOK, status, input)
+	}
+	if !slices.ContainsFunc(dag.Definition().Nodes, func(n NodeDefinition) bool { return n.Name == "merge" && n.EarlyStart }) {
+		t.Fatal("early start missing from definition")
+	}
+
+	// 没有强依赖的节点与根节点一同开始，取消运行时等待随之结束
+	block := &Node[int]{Name: "block", Processor: func(node IRuntimeNode, _ int) error {
+		<-node.Context().Done()
+		return node.Context().Err()
+	}}
+	var canceled atomic.Bool
+	waiter := &Node[int]{
+		Name:             "waiter",
+		WeakDependencies: []*Node[int]{block},
+		EarlyStart:       true,
+		Processor: func(node IRuntimeNode, _ int) error {
+			if _, ok := node.AwaitDep("block", time.Minute); !ok {
+				canceled.Store(true)
+			}
+			return nil
+		},
+	}
+	dag, err = NewDAG(waiter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	done := make(chan struct{})
+	go func() {
+		dag.RunContext(ctx, 0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("await not released by cancellation")
+	}
+	if !canceled.Load() {
+		t.Fatal("await should fail on cancellation")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	RegisterPolicy("test-fast-rpc", Policy{LocalTimeout: time.Second, MaxAttempts: 3})
+	var attempts atomic.Int32
+	preset := &Node[int]{Name: "preset", Policy: "test-fast-rpc", Processor: func(IRuntimeNode, int) error {
+		attempts.Add(1)
+		return errors.New("boom")
+	}}
+	// 节点自身设置的字段优先于预设
+	override := &Node[int]{Name: "override", Policy: "test-fast-rpc", MaxAttempts: 1}
+	dag, err := NewDAG(preset, override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 构建后修改预设不影响已构建的图
+	RegisterPolicy("test-fast-rpc", Policy{MaxAttempts: 5})
+	def := dag.Definition()
+	if n := def.Nodes[0]; n.LocalTimeout != time.Second || n.MaxAttempts != 3 || n.Policy != "test-fast-rpc" {
+		t.Fatal("unexpected preset node:", n)
+	}
+	if n := def.Nodes[1]; n.LocalTimeout != time.Second || n.MaxAttempts != 1 {
+		t.Fatal("unexpected override node:", n)
+	}
+	if r := ByNode(dag.Run(0), preset); r.Attempts != 3 || attempts.Load() != 3 {
+		t.Fatal("preset retries not applied:", r.Attempts, attempts.Load())
+	}
+	// 节点通过 PolicyOverrides 将预设开启的布尔字段关闭
+	RegisterPolicy("test-panic-retry", Policy{MaxAttempts: 3, RetryOnPanic: true, RetryQueueLimit: 1, RetryQueueFail: true})
+	var panics atomic.Int32
+	strict := &Node[int]{Name: "strict", Policy: "test-panic-retry", PolicyOverrides: PolicyRetryOnPanic | PolicyRetryQueueFail, Processor: func(IRuntimeNode, int) error {
+		panics.Add(1)
+		panic("boom")
+	}}
+	inherit := &Node[int]{Name: "inherit", Policy: "test-panic-retry"}
+	dag, err = NewDAG(strict, inherit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def = dag.Definition()
+	for _, n := range def.Nodes {
+		if overridden := n.Name == "strict"; n.RetryOnPanic == overridden || n.RetryQueueFail == overridden {
+			t.Fatal("unexpected override:", n)
+		}
+	}
+	if r := ByNode(dag.Run(0), strict); r.Status != Failed || r.Attempts != 1 || panics.Load() != 1 {
+		t.Fatal("panic should not be retried:", r.Attempts, panics.Load())
+	}
+
+	var unknownErr *UnknownPolicyErr
+	if _, err := NewDAG(&Node[int]{Name: "typo", Policy: "test-missing"}); !errors.As(err, &unknownErr) || unknownErr.NodeName != "typo" {
+		t.Fatal("unexpected err:", err)
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	a := &RunResult{Cost: 100 * time.Millisecond, Results: []*NodeResult{
+		{Name: "load", Status: Succeeded, Cost: 40 * time.Millisecond},
+		{Name: "save", Status: Failed, Cost: 10 * time.Millisecond},
+		{Name: "legacy", Status: Succeeded},
+	}}
+	b := &RunResult{Cost: 150 * time.Millisecond, Results: []*NodeResult{
+		{Name: "load", Status: Failed, Cost: 90 * time.Millisecond},
+		{Name: "save", Status: Succeeded, Cost: 10 * time.Millisecond},
+		{Name: "audit", Status: Succeeded},
+	}}
+	c := CompareRuns(a, b)
+	if c.CostDelta != 50*time.Millisecond || len(c.Nodes) != 2 {
+		t.Fatal("unexpected comparison:", c.CostDelta, c.Nodes)
+	}
+	if d := c.Nodes[0]; d.Name != "load" || d.StatusA != Succeeded || d.StatusB != Failed || d.CostDelta != 50*time.Millisecond || !d.NewFailure {
+		t.Fatal("unexpected load diff:", d)
+	}
+	if fmt.Sprint(c.NewFailures, c.Fixed, c.Added, c.Removed) != "[load] [save] [audit] [legacy]" {
+		t.Fatal("unexpected changes:", c.NewFailures, c.Fixed, c.Added, c.Removed)
+	}
+	if str := c.String(); !strings.Contains(str, "load") || !strings.Contains(str, "audit") {
+		t.Fatal("unexpected report:", str)
+	}
+}
+
+func TestIterators(t *testing.T) {
+	fail := func(IRuntimeNode, int) error { return errors.New("boom") }
+	a := &Node[int]{Name: "a", Processor: fail}
+	b := &Node[int]{Name: "b", WeakDependencies: []*Node[int]{a}, Processor: func(IRuntimeNode, int) error { return nil }}
+	c := &Node[int]{Name: "c", Dependencies: []*Node[int]{b}, Processor: fail}
+	dag, err := NewDAG(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 包括通过依赖关系间接加入的节点
+	var nodes []*Node[int]
+	for node := range dag.AllNodes() {
+		nodes = append(nodes, node)
+	}
+	if len(nodes) != 3 || nodes[0] != c || !slices.Contains(nodes, a) || !slices.Contains(nodes, b) {
+		t.Fatal("unexpected nodes:", len(nodes))
+	}
+	result := dag.Run(0)
+	var all, failed []string
+	for r := range result.All() {
+		all = append(all, r.Name)
+	}
+	for r := range result.Failed() {
+		failed = append(failed, r.Name)
+	}
+	if fmt.Sprint(all) != "[c b a]" || fmt.Sprint(failed) != "[c a]" {
+		t.Fatal("unexpected results:", all, failed)
+	}
+	// 提前结束遍历
+	for r := range result.Failed() {
+		if r.Name != "c" {
+			t.Fatal("unexpected first failure:", r.Name)
+		}
+		break
+	}
+}
+
+func TestInline(t *testing.T) {
+	newDAG := func(inline bool) *DAG[int] {
+		root := &Node[int]{Name: "root", Processor: func(IRuntimeNode, int) error { return nil }}
+		var nodes []*Node[int]
+		for i := range 3 {
+			nodes = append(nodes, &Node[int]{
+				Name:         "join" + strconv.Itoa(i),
+				Inline:       inline,
+				Dependencies: []*Node[int]{root},
+				Processor:    func(IRuntimeNode, int) error { return nil },
+			})
+		}
+		// 没有 processor 的节点总是内联执行
+		nodes = append(nodes, &Node[int]{Name: "anchor", Dependencies: []*Node[int]{root}})
+		dag, err := NewDAG(nodes...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dag
+	}
+	// 内联节点在上游节点的协程内执行，不提交到协程池
+	result := newDAG(true).RunWithOptions(0, RunOptions{Pool: NewPool(1)})
+	if err := result.Err(); err != nil || result.PoolStats.Started != 1 {
+		t.Fatal("inline nodes should not be submitted:", err, result.PoolStats.Started)
+	}
+	result = newDAG(false).RunWithOptions(0, RunOptions{Pool: NewPool(1)})
+	if err := result.Err(); err != nil || result.PoolStats.Started < 3 {
+		t.Fatal("unexpected submitted tasks:", err, result.PoolStats.Started)
+	}
+}
+
+func TestNodeMeta(t *testing.T) {
+	meta := map[string]any{"team": "search", "runbook": `http://wiki/"rank"`}
+	rank := &Node[int]{Name: "rank", Meta: meta}
+	dag, err := NewDAG(rank, &Node[int]{Name: "plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mermaid := dag.ToMermaid(); !strings.Contains(mermaid, `click 0 callback "runbook=http://wiki/#quot;rank#quot;, team=search"`) ||
+		strings.Contains(mermaid, "click 1") {
+		t.Fatal("unexpected mermaid:", mermaid)
+	}
+	data, err := json.Marshal(dag.Definition())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"meta":{"runbook":"http://wiki/\"rank\"","team":"search"}`) {
+		t.Fatal("unexpected definition:", string(data))
+	}
+	// 构建后修改用户节点的元数据不影响图
+	meta["team"] = "ads"
+	if r := ByNode(dag.Run(0), rank); r.Meta["team"] != "search" {
+		t.Fatal("unexpected result meta:", r.Meta)
+	}
+}
+
+func TestSampleRate(t *testing.T) {
+	var in, out string
+	for i := 0; in == "" || out == ""; i++ {
+		if id := "run-" + strconv.Itoa(i); sampled(id, 0.5) {
+			in = id
+		} else {
+			out = id
+		}
+	}
+	dag, err := NewDAG(&Node[int]{Name: "node", Processor: func(IRuntimeNode, int) error { return nil }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events atomic.Int32
+	sink := EventSinkFunc(func(*RunEvent) { events.Add(1) })
+	result := dag.RunWithOptions(0, RunOptions{RunID: in, SampleRate: 0.5, EventSink: sink})
+	if !result.Sampled || len(result.Concurrency) == 0 || events.Load() == 0 {
+		t.Fatal("sampled run should collect details:", result.Sampled, len(result.Concurrency), events.Load())
+	}
+	// 未被采样的运行仍记录汇总指标
+	events.Store(0)
+	result = dag.RunWithOptions(0, RunOptions{RunID: out, SampleRate: 0.5, EventSink: sink})
+	if result.Sampled || len(result.Concurrency) != 0 || events.Load() != 0 || result.PeakConcurrency != 1 {
+		t.Fatal("unsampled run should skip details:", result.Sampled, len(result.Concurrency), events.Load(), result.PeakConcurrency)
+	}
+	if result.AvgConcurrency() != 0 {
+		t.Fatal("unexpected average concurrency:", result.AvgConcurrency())
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"sync"
+)
+
+// DataBus 运行级的线程安全数据总线，每次运行创建一个，节点通过 Publish 写入、Subscribe 读取，
+// 主流程在运行结束后通过 RunResult.DataBus 与 Lookup 读取
+type DataBus struct {
+	mu     sync.RWMutex
+	values map[any]any
+}
+
+// BusKey 数据总线的键，类型参数 V 为值的类型，名称相同而类型不同的键互不冲突
+type BusKey[V any] struct {
+	name string
+}
+
+// NewBusKey 创建数据总线的键
+func NewBusKey[V any](name string) BusKey[V] {
+	return BusKey[V]{name: name}
+}
+
+// Name 获取键的名称
+func (key BusKey[V]) Name() string {
+	return key.name
+}
+
+func newDataBus() *DataBus {
+	return &DataBus{values: make(map[any]any)}
+}
+
+func (bus *DataBus) set(key any, value any) {
+	bus.mu.Lock()
+	bus.values[key] = value
+	bus.mu.Unlock()
+}
+
+func (bus *DataBus) get(key any) (any, bool) {
+	if bus == nil {
+		return nil, false
+	}
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+	value, ok := bus.values[key]
+	return value, ok
+}
+
+// Len 获取数据总线中的键数量
+func (bus *DataBus) Len() int {
+	if bus == nil {
+		return 0
+	}
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+	return len(bus.values)
+}
+
+// Publish 通过 DoIfRunning 向本次运行的数据总线写入数据，节点超时或已结束时写入被拒绝并返回 false
+func Publish[V any](node IRuntimeNode, key BusKey[V], value V) bool {
+	bus := node.DataBus()
+	if bus == nil {
+		return false
+	}
+	return node.DoIfRunning(func() {
+		bus.set(key, value)
+	})
+}
+
+// Subscribe 读取本次运行的数据总线中的数据，不存在时返回 false
+func Subscribe[V any](node IRuntimeNode, key BusKey[V]) (V, bool) {
+	return Lookup(node.DataBus(), key)
+}
+
+// Lookup 读取数据总线中的数据，bus 为 nil 或不存在时返回 false
+func Lookup[V any](bus *DataBus, key BusKey[V]) (V, bool) {
+	value, ok := bus.get(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	v, ok := value.(V)
+	return v, ok
+}
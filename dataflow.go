@@ -0,0 +1,69 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+// NewDataNode 创建数据流节点，fn 的返回值作为节点的输出，下游节点通过 InputOf 按名称读取，
+// 节点间无需通过共享的 params 传递数据，也就无需自行加锁
+func NewDataNode[T, O any](name string, fn func(node IRuntimeNode, params T) (O, error)) *Node[T] {
+	return &Node[T]{
+		Name: name,
+		Processor: func(node IRuntimeNode, params T) error {
+			output, err := fn(node, params)
+			if err != nil {
+				return err
+			}
+			node.SetOutput(output)
+			return nil
+		},
+	}
+}
+
+// InputOf 读取名为 dep 的直接依赖节点的输出，依赖不存在、未结束、没有输出或类型不匹配时返回 false。
+// 依赖节点的输出从检查点恢复时（见 DAG.Resume），按类型 O 反序列化
+func InputOf[O any](node IRuntimeNode, dep string) (O, bool) {
+	var zero O
+	v, ok := node.Input(dep)
+	if !ok {
+		return zero, false
+	}
+	if encoded, isEncoded := v.(*EncodedOutput); isEncoded {
+		var output O
+		if err := encoded.Decode(&output); err != nil {
+			return zero, false
+		}
+		return output, true
+	}
+	output, ok := v.(O)
+	return output, ok
+}
+
+func (node *runtimeNode[T]) SetOutput(output any) {
+	node.DoIfRunning(func() {
+		node.output = output
+	})
+}
+
+func (node *runtimeNode[T]) Input(name string) (any, bool) {
+	for _, idx := range node.deps {
+		if node.dagRun.dag.metaNodes[idx].name != name {
+			continue
+		}
+		dep := node.dagRun.peek(idx)
+		if dep == nil {
+			return nil, false
+		}
+		select {
+		case <-dep.finished:
+		default:
+			return nil, false
+		}
+		if dep.reused != nil {
+			return dep.reused.Output, dep.reused.Output != nil
+		}
+		return dep.output, dep.output != nil
+	}
+	return nil, false
+}
@@ -0,0 +1,84 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// NodeDefinition 节点配置的快照，依赖关系以节点名称表示，不持有用户传入的 *Node 指针
+type NodeDefinition struct {
+	Name             string            `json:"name"`
+	Config           map[string]string `json:"config,omitempty"`
+	RequiredConfig   []string          `json:"required_config,omitempty"`
+	Meta             map[string]any    `json:"meta,omitempty"`
+	Owner            string            `json:"owner,omitempty"`
+	AlertChannel     string            `json:"alert_channel,omitempty"`
+	Tags             []string          `json:"tags,omitempty"`
+	Policy           string            `json:"policy,omitempty"`
+	LocalTimeout     time.Duration     `json:"local_timeout,omitempty"`
+	InheritTimeout   bool              `json:"inherit_timeout,omitempty"`
+	TotalTimeout     time.Duration     `json:"total_timeout,omitempty"`
+	Weight           float64           `json:"weight,omitempty"`
+	Priority         int               `json:"priority,omitempty"`
+	MaxAttempts      uint              `json:"max_attempts,omitempty"`
+	RetryOnPanic     bool              `json:"retry_on_panic,omitempty"`
+	RetryQueueLimit  int               `json:"retry_queue_limit,omitempty"`
+	RetryQueueFail   bool              `json:"retry_queue_fail,omitempty"`
+	MaxConcurrent    int               `json:"max_concurrent,omitempty"`
+	Resources        []string          `json:"resources,omitempty"`
+	SLA              *NodeSLA          `json:"sla,omitempty"`
+	Dependencies     []string          `json:"dependencies,omitempty"`
+	WeakDependencies []string          `json:"weak_dependencies,omitempty"`
+	EarlyStart       bool              `json:"early_start,omitempty"`
+}
+
+// Definition 图定义的快照，Nodes 的顺序与图内节点顺序一致
+type Definition struct {
+	Nodes          []NodeDefinition `json:"nodes"`
+	SLAMaxDuration time.Duration    `json:"sla_max_duration,omitempty"`
+}
+
+// Definition 返回图定义的深拷贝，可用于导出或重建图结构
+func (dag *DAG[T]) Definition() *Definition {
+	def := &Definition{Nodes: make([]NodeDefinition, len(dag.metaNodes)), SLAMaxDuration: dag.slaMaxDuration}
+	for i, node := range dag.metaNodes {
+		def.Nodes[i] = NodeDefinition{
+			Name:            node.name,
+			Config:          maps.Clone(node.config),
+			RequiredConfig:  slices.Clone(node.required),
+			Meta:            maps.Clone(node.meta),
+			Owner:           node.owner,
+			AlertChannel:    node.alertChannel,
+			Tags:            slices.Clone(node.tags),
+			Policy:          node.policy,
+			LocalTimeout:    node.localTimeout,
+			InheritTimeout:  node.inherit,
+			TotalTimeout:    node.totalTimeout,
+			Weight:          node.weight,
+			Priority:        node.priority,
+			MaxAttempts:     node.maxAttempts,
+			RetryOnPanic:    node.retryOnPanic,
+			RetryQueueLimit: node.retryQueueLimit,
+			RetryQueueFail:  node.retryQueueFail,
+			MaxConcurrent:   cap(node.slots),
+			Resources:       slices.Clone(node.resources),
+			SLA:             node.slaDefinition(),
+			EarlyStart:      node.earlyStart,
+		}
+	}
+	for _, node := range dag.metaNodes {
+		for _, childIdx := range node.children {
+			def.Nodes[childIdx].Dependencies = append(def.Nodes[childIdx].Dependencies, node.name)
+		}
+		for _, weakChildIdx := range node.weakChildren {
+			def.Nodes[weakChildIdx].WeakDependencies = append(def.Nodes[weakChildIdx].WeakDependencies, node.name)
+		}
+	}
+	return def
+}
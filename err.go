@@ -5,6 +5,12 @@
 
 package easydag
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 type strErr string
 
 func (e strErr) Error() string {
@@ -12,3 +18,171 @@ func (e strErr) Error() string {
 }
 
 const TimeoutErr = strErr("timeout")
+
+// timeoutErr 区分截止时间的超时错误，满足 errors.Is(err, TimeoutErr)
+type timeoutErr string
+
+func (e timeoutErr) Error() string {
+	return string(e)
+}
+
+func (e timeoutErr) Is(target error) bool {
+	return target == TimeoutErr
+}
+
+const (
+	// LocalTimeoutErr 节点的本地超时时间（Node.LocalTimeout，或 InheritTimeout 分配的时间）先到期
+	LocalTimeoutErr = timeoutErr("local timeout")
+	// TotalTimeoutErr 节点的全局超时时间（Node.TotalTimeout）或运行的整体超时时间（RunOptions.Timeout）先到期
+	TotalTimeoutErr = timeoutErr("total timeout")
+)
+
+// TimeoutError 节点超时的详细信息，满足 errors.Is(err, TimeoutErr)，并按触发的截止时间满足 errors.Is(err, LocalTimeoutErr) 或 errors.Is(err, TotalTimeoutErr)
+type TimeoutError struct {
+	NodeName string
+	Deadline error         // 触发的截止时间，为 LocalTimeoutErr 或 TotalTimeoutErr
+	Timeout  time.Duration // 生效的超时时间，即本地超时时间与全局超时剩余时间中的较小值
+	Elapsed  time.Duration // 超时时节点已执行的时间
+	Attempt  uint          // 超时时的运行次数
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("node %s %v after %v (timeout %v, attempt %d)", e.NodeName, e.Deadline, e.Elapsed, e.Timeout, e.Attempt)
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == TimeoutErr || target == e.Deadline
+}
+
+// QuarantinedErr 节点连续失败次数达到阈值而被隔离，本次运行未执行，节点以 Skipped 结束
+const QuarantinedErr = strErr("quarantined")
+
+// DisabledErr 节点在本次运行中被 RunOptions.Disabled 禁用
+const DisabledErr = strErr("disabled")
+
+// AdmissionErr 节点被准入控制跳过
+type AdmissionErr struct {
+	NodeName string
+	Reason   string
+}
+
+func (e *AdmissionErr) Error() string {
+	return fmt.Sprintf("node %s was not admitted: %s", e.NodeName, e.Reason)
+}
+
+// UnroutableErr 路由节点的 processor 收到的 IRuntimeNode 不是运行时节点（如被中间件包装），无法记录路由结果
+const UnroutableErr = strErr("router node requires the runtime node passed to its processor")
+
+// OperatorKilledErr 节点被 RunHandle.Kill 终止
+const OperatorKilledErr = strErr("killed by operator")
+
+// LeaseLostErr Exclusive 节点执行期间租约续约失败（过期或被其他持有者获取），节点被终止
+const LeaseLostErr = strErr("lease lost")
+
+// NoCheckpointStoreErr 调用 DAG.Resume 时未设置 RunOptions.Checkpoint
+const NoCheckpointStoreErr = strErr("no checkpoint store")
+
+// ErrNoProgress 所有根节点均失败，图无法继续推进
+const ErrNoProgress = strErr("no progress")
+
+// ErrFailFast 开启 RunOptions.FailFast 时，因节点失败而终止运行
+const ErrFailFast = strErr("fail fast")
+
+// FailFastErr 因节点失败而终止运行的原因，满足 errors.Is(err, ErrFailFast)，可通过 errors.Is 匹配节点的错误
+type FailFastErr struct {
+	NodeName string
+	Err      error
+}
+
+func (e *FailFastErr) Error() string {
+	return fmt.Sprintf("%s: node %s failed: %v", string(ErrFailFast), e.NodeName, e.Err)
+}
+
+func (e *FailFastErr) Is(target error) bool {
+	return target == ErrFailFast
+}
+
+func (e *FailFastErr) Unwrap() error {
+	return e.Err
+}
+
+// PanicErr processor 发生 panic 时返回的错误，用于区分崩溃与普通业务错误
+type PanicErr struct {
+	NodeName string
+	Value    any
+	Stack    []byte // 发生 panic 时的堆栈，见 debug.Stack
+}
+
+func (e *PanicErr) Error() string {
+	if len(e.Stack) == 0 {
+		return fmt.Sprintf("recover panic over node %s: %v", e.NodeName, e.Value)
+	}
+	return fmt.Sprintf("recover panic over node %s: %v\n%s", e.NodeName, e.Value, e.Stack)
+}
+
+// NoProgressErr 所有根节点均失败时的错误，汇总了各根节点的错误，满足 errors.Is(err, ErrNoProgress)
+type NoProgressErr struct {
+	Roots []string // 根节点名称
+	Errs  []error  // 与 Roots 一一对应的错误
+}
+
+func (e *NoProgressErr) Error() string {
+	var str strings.Builder
+	str.WriteString(string(ErrNoProgress))
+	str.WriteString(": all root nodes failed")
+	for i, root := range e.Roots {
+		str.WriteString(fmt.Sprintf("; %s: %v", root, e.Errs[i]))
+	}
+	return str.String()
+}
+
+func (e *NoProgressErr) Is(target error) bool {
+	return target == ErrNoProgress
+}
+
+func (e *NoProgressErr) Unwrap() []error {
+	return e.Errs
+}
+
+// NodeError 节点失败的错误，记录失败的节点与最后一次执行的次数，可通过 errors.As 找到失败的节点，
+// 也可通过 errors.Is、errors.As 匹配节点的原始错误，如 errors.Is(err, TimeoutErr)
+type NodeError struct {
+	NodeName string
+	Attempt  uint // 节点的运行次数，未执行 processor 时为0
+	Cause    error
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("node %s failed on attempt %d: %v", e.NodeName, e.Attempt, e.Cause)
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Cause
+}
+
+// RunErr 图运行失败时的汇总错误，包含运行被终止的原因与各失败节点的错误，
+// 可通过 errors.Is、errors.As 匹配其中任意一个错误，如 errors.Is(err, TimeoutErr)
+type RunErr struct {
+	Cause error    // 运行被终止的原因，未被终止时为 nil
+	Nodes []string // 失败节点的名称
+	Errs  []error  // 与 Nodes 一一对应的错误，类型为 *NodeError
+}
+
+func (e *RunErr) Error() string {
+	var str strings.Builder
+	str.WriteString("dag run failed")
+	if e.Cause != nil {
+		str.WriteString(fmt.Sprintf(": cancelled: %v", e.Cause))
+	}
+	for _, err := range e.Errs {
+		str.WriteString(fmt.Sprintf("; %v", err))
+	}
+	return str.String()
+}
+
+func (e *RunErr) Unwrap() []error {
+	if e.Cause == nil {
+		return e.Errs
+	}
+	return append([]error{e.Cause}, e.Errs...)
+}
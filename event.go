@@ -0,0 +1,102 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"time"
+)
+
+// RunEventVersion RunEvent 的结构版本，字段发生不兼容变更时递增
+const RunEventVersion = 1
+
+// RunEventType 运行事件的类型
+type RunEventType string
+
+const (
+	EventRunStarted   RunEventType = "run.started"   // 图开始运行
+	EventNodeStarted  RunEventType = "node.started"  // 节点开始执行 processor
+	EventNodeFinished RunEventType = "node.finished" // 节点结束，包括跳过、取消、复用历史结果
+	EventSLABreached  RunEventType = "sla.breached"  // 图运行结束时发现的 SLA 违约，在 run.finished 之前发送
+	EventRunFinished  RunEventType = "run.finished"  // 图运行结束
+)
+
+// RunEvent 运行事件，结构稳定且带版本号，可直接序列化为 JSON 供外部工具消费
+type RunEvent struct {
+	Version      int               `json:"version"`
+	Type         RunEventType      `json:"type"`
+	Time         time.Time         `json:"time"`
+	RunID        string            `json:"run_id"`
+	ParentRunID  string            `json:"parent_run_id,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Node         string            `json:"node,omitempty"`          // 节点名称，运行级事件为空
+	Status       NodeStatus        `json:"status,omitempty"`        // 节点结束时的状态，仅 node.finished 事件有效
+	Owner        string            `json:"owner,omitempty"`         // 节点的负责人，仅 node.finished 事件有效
+	AlertChannel string            `json:"alert_channel,omitempty"` // 节点的告警渠道，仅 node.finished 事件有效
+	Tags         []string          `json:"tags,omitempty"`          // 节点的标签，可作为指标的维度，仅 node.finished 事件有效
+	Attempts     uint              `json:"attempts,omitempty"`
+	Cost         time.Duration     `json:"cost,omitempty"`   // 节点或运行的耗时（纳秒），仅结束事件有效
+	Error        string            `json:"error,omitempty"`  // 节点的错误或运行被终止的原因，已经过 ErrSanitizer 脱敏
+	Breach       *SLABreach        `json:"breach,omitempty"` // SLA 违约，仅 sla.breached 事件有效
+}
+
+// EventSink 接收运行事件，事件可能被并发发送，实现需保证并发安全
+type EventSink interface {
+	OnEvent(event *RunEvent)
+}
+
+// EventSinkFunc 函数形式的 EventSink
+type EventSinkFunc func(event *RunEvent)
+
+func (f EventSinkFunc) OnEvent(event *RunEvent) {
+	f(event)
+}
+
+// newEvent 创建本次运行的事件，未设置 EventSink 时返回 nil
+func (ctx *dagCtx) newEvent(typ RunEventType) *RunEvent {
+	if ctx.eventSink == nil || !ctx.sampled {
+		return nil
+	}
+	return &RunEvent{
+		Version:     RunEventVersion,
+		Type:        typ,
+		Time:        time.Now(),
+		RunID:       ctx.runID,
+		ParentRunID: ctx.parentRunID,
+		Labels:      ctx.labels,
+	}
+}
+
+// emitSLABreaches 为每个 SLA 违约发送 sla.breached 事件
+func (ctx *dagCtx) emitSLABreaches(breaches []SLABreach) {
+	for i := range breaches {
+		event := ctx.newEvent(EventSLABreached)
+		if event == nil {
+			return
+		}
+		event.Node = breaches[i].Node
+		event.Breach = &breaches[i]
+		ctx.eventSink.OnEvent(event)
+	}
+}
+
+// emitNodeFinished 由节点结果创建并发送 node.finished 事件
+func (ctx *dagCtx) emitNodeFinished(result *NodeResult) {
+	event := ctx.newEvent(EventNodeFinished)
+	if event == nil {
+		return
+	}
+	event.Node = result.Name
+	event.Status = result.Status
+	event.Owner = result.Owner
+	event.AlertChannel = result.AlertChannel
+	event.Tags = result.Tags
+	event.Attempts = result.Attempts
+	event.Cost = result.Cost
+	if result.Err != nil {
+		event.Error = result.Err.Error()
+	}
+	ctx.eventSink.OnEvent(event)
+}
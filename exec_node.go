@@ -0,0 +1,120 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// execOutputLimit 命令标准输出与标准错误各自最多保留的字节数，超出部分被丢弃
+	execOutputLimit = 1 << 20
+	// execWaitDelay 命令被终止后等待其输出管道关闭的最长时间，避免遗留的子进程持有管道使节点无法结束
+	execWaitDelay = 5 * time.Second
+)
+
+// ExecOutput 命令节点的输出，命令以非0状态码退出时同样会设置
+type ExecOutput struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+	// Truncated 标准输出或标准错误超过 1MiB 时为 true，超出部分被丢弃
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// cappedBuffer 最多保留 limit 字节的 io.Writer，超出部分被丢弃但仍视为写入成功，使命令不会因输出过多而失败
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:max(room, 0)])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// ExecErr 命令以非0状态码退出时的错误，可通过 errors.As 获取 *exec.ExitError
+type ExecErr struct {
+	NodeName string
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+func (e *ExecErr) Error() string {
+	return fmt.Sprintf("node %s: command exited with code %d: %s", e.NodeName, e.ExitCode, strings.TrimSpace(e.Stderr))
+}
+
+func (e *ExecErr) Unwrap() error {
+	return e.Err
+}
+
+// NewExecNode 创建执行外部命令的节点，command 与 args 均为 text/template 模板，运行时以 params 渲染。
+// 命令的标准输出、标准错误与状态码作为节点的输出（见 ExecOutput），下游节点可通过 InputOf 读取。
+// 命令在 IRuntimeNode.Context 下执行，节点超时或运行被取消时命令被终止（unix 平台上终止整个进程组），节点以 context.Cause 失败。
+// 标准输出与标准错误各自最多保留 1MiB
+func NewExecNode[T any](name string, command string, args ...string) (*Node[T], error) {
+	tmpls := make([]*template.Template, 0, len(args)+1)
+	for i, text := range append([]string{command}, args...) {
+		tmpl, err := template.New(fmt.Sprintf("%s/%d", name, i)).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		tmpls = append(tmpls, tmpl)
+	}
+	return &Node[T]{
+		Name: name,
+		Processor: func(node IRuntimeNode, params T) error {
+			argv := make([]string, len(tmpls))
+			for i, tmpl := range tmpls {
+				var buf strings.Builder
+				if err := tmpl.Execute(&buf, params); err != nil {
+					return err
+				}
+				argv[i] = buf.String()
+			}
+			stdout := &cappedBuffer{limit: execOutputLimit}
+			stderr := &cappedBuffer{limit: execOutputLimit}
+			cmd := exec.CommandContext(node.Context(), argv[0], argv[1:]...)
+			cmd.Stdout = stdout
+			cmd.Stderr = stderr
+			cmd.WaitDelay = execWaitDelay
+			setProcessGroup(cmd)
+			err := cmd.Run()
+			if node.Context().Err() != nil {
+				return context.Cause(node.Context())
+			}
+			var exitErr *exec.ExitError
+			if err != nil && !errors.As(err, &exitErr) {
+				// 命令未能启动
+				return err
+			}
+			output := ExecOutput{
+				Stdout:    stdout.buf.String(),
+				Stderr:    stderr.buf.String(),
+				ExitCode:  cmd.ProcessState.ExitCode(),
+				Truncated: stdout.truncated || stderr.truncated,
+			}
+			node.SetOutput(output)
+			if exitErr != nil {
+				return &ExecErr{NodeName: node.GetName(), ExitCode: output.ExitCode, Stderr: output.Stderr, Err: err}
+			}
+			return nil
+		},
+	}, nil
+}
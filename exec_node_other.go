@@ -0,0 +1,13 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package easydag
+
+import "os/exec"
+
+// setProcessGroup 非 unix 平台不支持进程组，终止时只结束命令进程本身
+func setProcessGroup(*exec.Cmd) {}
@@ -0,0 +1,21 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package easydag
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 使命令在独立的进程组中运行，终止时向整个进程组发送 SIGKILL，避免遗留子进程
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"cmp"
+	"encoding/binary"
+	"hash/fnv"
+	"slices"
+	"time"
+)
+
+// Fingerprint 获取图结构与节点配置的指纹，由各节点的校验和按图内顺序计算，相同的指纹表示运行的是同一版本的图
+func (dag *DAG[T]) Fingerprint() uint64 {
+	return dag.fingerprint
+}
+
+// fingerprint 按图内顺序合并各节点的校验和，设置了图运行总耗时的目标时一并计入
+func fingerprint[T any](metaNodes []*nodeMetadata[T], sla time.Duration) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, node := range metaNodes {
+		binary.LittleEndian.PutUint64(buf[:], node.checksum)
+		_, _ = h.Write(buf[:])
+	}
+	if sla > 0 {
+		binary.LittleEndian.PutUint64(buf[:], uint64(sla))
+		_, _ = h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+// FlakyNode 在同一版本的图（指纹相同）的多次运行间成功与失败频繁翻转的节点
+type FlakyNode struct {
+	Fingerprint uint64  `json:"fingerprint"`
+	Name        string  `json:"name"`
+	Owner       string  `json:"owner,omitempty"`
+	Runs        int     `json:"runs"`     // 节点成功或失败的运行数，其余状态不计入
+	Failures    int     `json:"failures"` // 节点失败的运行数
+	Flips       int     `json:"flips"`    // 相邻两次运行之间结果翻转的次数
+	FlakeRate   float64 `json:"flake_rate"`
+}
+
+// FlakyOptions RunArchive.Flaky 的配置
+type FlakyOptions struct {
+	// MinRuns 节点至少需要的运行数，运行数过少时翻转率没有意义，小于2时为2
+	MinRuns int
+	// MinRate 报告的最小翻转率，取值在 [0, 1] 之间
+	MinRate float64
+}
+
+// Flaky 分析归档中保留完整结果的运行，按指纹分组，找出结果频繁翻转的节点，按翻转率从高到低排列。
+// 翻转率为翻转次数除以可能翻转的次数（运行数减1），总是失败或总是成功的节点翻转率为0
+func (a *RunArchive) Flaky(opts FlakyOptions) []FlakyNode {
+	minRuns := max(opts.MinRuns, 2)
+	type key struct {
+		fingerprint uint64
+		name        string
+	}
+	stats := make(map[key]*FlakyNode)
+	last := make(map[key]NodeStatus)
+	for _, result := range a.Runs() {
+		for _, r := range result.Results {
+			if r.Status != Succeeded && r.Status != Failed {
+				continue
+			}
+			k := key{result.Fingerprint, r.Name}
+			s := stats[k]
+			if s == nil {
+				s = &FlakyNode{Fingerprint: result.Fingerprint, Name: r.Name, Owner: r.Owner}
+				stats[k] = s
+			} else if last[k] != r.Status {
+				s.Flips++
+			}
+			last[k] = r.Status
+			s.Runs++
+			if r.Status == Failed {
+				s.Failures++
+			}
+		}
+	}
+	var flaky []FlakyNode
+	for _, s := range stats {
+		if s.Runs < minRuns {
+			continue
+		}
+		s.FlakeRate = float64(s.Flips) / float64(s.Runs-1)
+		if s.Flips > 0 && s.FlakeRate >= opts.MinRate {
+			flaky = append(flaky, *s)
+		}
+	}
+	slices.SortFunc(flaky, func(x, y FlakyNode) int {
+		return cmp.Or(
+			cmp.Compare(y.FlakeRate, x.FlakeRate),
+			cmp.Compare(x.Name, y.Name),
+			cmp.Compare(x.Fingerprint, y.Fingerprint),
+		)
+	})
+	return flaky
+}
@@ -1,3 +1,3 @@
 module github.com/china-tjj/easy-dag
 
-go 1.18
+go 1.23
@@ -0,0 +1,123 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// HTTPRequest HTTP 节点的请求配置
+type HTTPRequest[T any] struct {
+	// Method 请求方法，为空时为 GET
+	Method string
+	// URL 请求地址，为 text/template 模板，运行时以 params 渲染
+	URL string
+	// Header 请求头，在各次请求间共享，不可修改
+	Header http.Header
+	// Body 根据 params 生成请求体，为 nil 时没有请求体
+	Body func(params T) ([]byte, error)
+	// Expect 期望的状态码，响应状态码不在其中时节点以 *HTTPStatusErr 失败，为空时期望 2xx
+	Expect []int
+	// Client 发送请求的客户端，为 nil 时使用 http.DefaultClient
+	Client *http.Client
+}
+
+// HTTPOutput HTTP 节点的输出，状态码不符合期望时同样会设置
+type HTTPOutput struct {
+	StatusCode int           `json:"status_code"`
+	Header     http.Header   `json:"header,omitempty"`
+	Body       []byte        `json:"body,omitempty"`
+	Latency    time.Duration `json:"latency"` // 发送请求到读取完响应体的耗时
+}
+
+// HTTPStatusErr 响应状态码不符合期望时的错误
+type HTTPStatusErr struct {
+	NodeName   string
+	StatusCode int
+}
+
+func (e *HTTPStatusErr) Error() string {
+	return fmt.Sprintf("node %s: unexpected http status %d", e.NodeName, e.StatusCode)
+}
+
+// NewHTTPNode 创建发送 HTTP 请求的节点，请求的超时时间取节点截止时间与运行截止时间中较早者（见 IRuntimeNode.RemainingBudget），
+// 状态码、响应头、响应体与耗时作为节点的输出（见 HTTPOutput），下游节点可通过 InputOf 读取
+func NewHTTPNode[T any](name string, req HTTPRequest[T]) (*Node[T], error) {
+	tmpl, err := template.New(name).Parse(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	client := req.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Node[T]{
+		Name: name,
+		Processor: func(node IRuntimeNode, params T) error {
+			var url strings.Builder
+			if err := tmpl.Execute(&url, params); err != nil {
+				return err
+			}
+			var body io.Reader
+			if req.Body != nil {
+				data, err := req.Body(params)
+				if err != nil {
+					return err
+				}
+				body = bytes.NewReader(data)
+			}
+			ctx := node.Context()
+			if budget := node.RemainingBudget(); budget != math.MaxInt64 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, budget)
+				defer cancel()
+			}
+			httpReq, err := http.NewRequestWithContext(ctx, method, url.String(), body)
+			if err != nil {
+				return err
+			}
+			for key, values := range req.Header {
+				httpReq.Header[key] = values
+			}
+			begin := time.Now()
+			resp, err := client.Do(httpReq)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return err
+			}
+			node.SetOutput(HTTPOutput{StatusCode: resp.StatusCode, Header: resp.Header, Body: data, Latency: time.Since(begin)})
+			if expectedStatus(req.Expect, resp.StatusCode) {
+				return nil
+			}
+			return &HTTPStatusErr{NodeName: node.GetName(), StatusCode: resp.StatusCode}
+		},
+	}, nil
+}
+
+// expectedStatus 判断状态码是否符合期望，expect 为空时期望 2xx
+func expectedStatus(expect []int, statusCode int) bool {
+	if len(expect) == 0 {
+		return statusCode >= 200 && statusCode < 300
+	}
+	return slices.Contains(expect, statusCode)
+}
@@ -0,0 +1,154 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LeaseStore 租约存储，为 Exclusive 节点提供跨进程的互斥，通常基于 Redis、etcd 等共享存储实现，实现需保证并发安全
+type LeaseStore interface {
+	// TryAcquire 尝试以 holder 的身份获取 key 的租约，租约在 ttl 后自动过期，已被其他 holder 持有且未过期时返回 false
+	TryAcquire(ctx context.Context, key, holder string, ttl time.Duration) (bool, error)
+	// Renew 将 holder 持有的租约延长 ttl，租约已过期或被其他 holder 持有时返回 false
+	Renew(ctx context.Context, key, holder string, ttl time.Duration) (bool, error)
+	// Release 释放 holder 持有的租约，租约不属于 holder 时不做任何操作
+	Release(ctx context.Context, key, holder string) error
+}
+
+const (
+	defaultLeaseTTL = 30 * time.Second
+	// leaseRetryInterval 租约被占用或获取出错时重试的间隔
+	leaseRetryInterval = 100 * time.Millisecond
+	// minLeaseRenewInterval 续约间隔的下限，避免极短的 LeaseTTL 使续约间隔为0
+	minLeaseRenewInterval = time.Millisecond
+)
+
+// leaseSeq 租约获取的序号，使每次获取的持有者标识唯一
+var leaseSeq atomic.Uint64
+
+// leaseKey 返回节点租约的键，dag 为图的名称（见 DAG.Name），避免不同图中的同名节点互斥
+func leaseKey(dag, name string) string {
+	return "easydag/lease/" + dag + "/" + name
+}
+
+// lease 节点持有的租约，执行期间在后台定期续约
+type lease struct {
+	store  LeaseStore
+	key    string
+	holder string
+	lost   func() // 续约失败时调用
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// acquireLease 获取节点的租约，租约被占用时等待其释放或过期，运行被终止时返回 nil。
+// 持有者标识为 RunOptions.LeaseHolder/运行ID/序号，同一进程内的并发运行与同一运行内的同名节点之间同样互斥。
+// 持有期间续约失败时调用 lost
+func (ctx *dagCtx) acquireLease(dag, name string, lost func()) *lease {
+	l := &lease{
+		store:  ctx.leases,
+		key:    leaseKey(dag, name),
+		holder: ctx.leaseHolder + "/" + strconv.FormatUint(leaseSeq.Add(1), 10),
+		lost:   lost,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for {
+		// 存储出错时视为未获取，继续重试
+		if ok, err := l.store.TryAcquire(ctx.ctx, l.key, l.holder, ctx.leaseTTL); ok && err == nil {
+			break
+		}
+		timer := time.NewTimer(leaseRetryInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.ctx.Done():
+			timer.Stop()
+			return nil
+		}
+	}
+	go l.renew(ctx.leaseTTL)
+	return l
+}
+
+// renew 每隔 ttl/3（不小于 minLeaseRenewInterval）续约一次，租约被拒绝续约或超过 ttl 未能续约成功（存储持续出错）时视为丢失，调用 lost 后停止续约
+func (l *lease) renew(ttl time.Duration) {
+	defer close(l.done)
+	ticker := time.NewTicker(max(ttl/3, minLeaseRenewInterval))
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+			ok, err := l.store.Renew(context.Background(), l.key, l.holder, ttl)
+			if ok && err == nil {
+				renewed = time.Now()
+				continue
+			}
+			if err == nil || time.Since(renewed) >= ttl {
+				l.lost()
+				return
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// release 停止续约并释放租约
+func (l *lease) release() {
+	close(l.stop)
+	<-l.done
+	_ = l.store.Release(context.Background(), l.key, l.holder)
+}
+
+// MemoryLeaseStore 进程内的租约存储，适用于测试或单进程内多个图共享互斥
+type MemoryLeaseStore struct {
+	mu     sync.Mutex
+	leases map[string]memoryLease
+}
+
+type memoryLease struct {
+	holder string
+	expire time.Time
+}
+
+func NewMemoryLeaseStore() *MemoryLeaseStore {
+	return &MemoryLeaseStore{leases: make(map[string]memoryLease)}
+}
+
+func (s *MemoryLeaseStore) TryAcquire(_ context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.leases[key]; ok && cur.holder != holder && time.Now().Before(cur.expire) {
+		return false, nil
+	}
+	s.leases[key] = memoryLease{holder: holder, expire: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryLeaseStore) Renew(_ context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.leases[key]; !ok || cur.holder != holder || time.Now().After(cur.expire) {
+		return false, nil
+	}
+	s.leases[key] = memoryLease{holder: holder, expire: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryLeaseStore) Release(_ context.Context, key, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.leases[key]; ok && cur.holder == holder {
+		delete(s.leases, key)
+	}
+	return nil
+}
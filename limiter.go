@@ -0,0 +1,43 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"context"
+)
+
+// Limiter 进程级的并发限制器，可被多个图的多次运行共享，限制同时执行的节点总数
+type Limiter struct {
+	slots chan struct{}
+}
+
+// NewLimiter 创建最多允许 maxRunning 个节点同时执行的限制器，maxRunning 小于1时被视为1
+func NewLimiter(maxRunning int) *Limiter {
+	if maxRunning < 1 {
+		maxRunning = 1
+	}
+	return &Limiter{slots: make(chan struct{}, maxRunning)}
+}
+
+// Acquire 获取一个执行名额，ctx 结束时返回 false
+func (l *Limiter) Acquire(ctx context.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Release 归还一个执行名额
+func (l *Limiter) Release() {
+	<-l.slots
+}
+
+// Running 当前正在执行的节点数
+func (l *Limiter) Running() int {
+	return len(l.slots)
+}
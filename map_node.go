@@ -0,0 +1,117 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MapErr 映射节点中部分子任务失败时的错误，可通过 errors.Is、errors.As 匹配任一子任务的错误
+type MapErr struct {
+	NodeName string
+	Items    []int   // 失败的子任务下标
+	Errs     []error // 与 Items 一一对应的错误
+}
+
+func (e *MapErr) Error() string {
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("node %s: %d of map items failed", e.NodeName, len(e.Items)))
+	for i, item := range e.Items {
+		str.WriteString(fmt.Sprintf("; item %d: %v", item, e.Errs[i]))
+	}
+	return str.String()
+}
+
+func (e *MapErr) Unwrap() []error {
+	return e.Errs
+}
+
+// NewMapNode 创建映射节点，运行时由 items 根据 params 生成子任务列表（如每个分片、每个用户一个），
+// 并行执行 fn 后再汇合，所有子任务结束后下游节点才会运行。maxParallel 为最大并行数，小于或等于0时表示不限制。
+// 节点超时或运行被取消后不再启动新的子任务，尚未启动的子任务以 context.Cause 失败
+func NewMapNode[T, E any](name string, items func(params T) []E, fn func(node IRuntimeNode, params T, item E) error, maxParallel int) *Node[T] {
+	return &Node[T]{
+		Name: name,
+		Processor: func(node IRuntimeNode, params T) error {
+			_, err := runMap(node, params, items(params), func(node IRuntimeNode, params T, item E) (struct{}, error) {
+				return struct{}{}, fn(node, params, item)
+			}, maxParallel)
+			return err
+		},
+	}
+}
+
+// NewMapReduceNodes 创建映射节点与依赖它的归约节点，映射节点各子任务的输出按子任务下标排列，作为映射节点的输出（见 SetOutput）传给 reducer，
+// 归约完成后下游节点才会运行。下游节点应依赖 reduceNode，且不可覆盖两个节点的 Processor
+func NewMapReduceNodes[T, E, R any](name string, items func(params T) []E, mapper func(node IRuntimeNode, params T, item E) (R, error),
+	reducer func(node IRuntimeNode, params T, outputs []R) error, maxParallel int) (mapNode, reduceNode *Node[T]) {
+	mapNode = &Node[T]{
+		Name: name,
+		Processor: func(node IRuntimeNode, params T) error {
+			results, err := runMap(node, params, items(params), mapper, maxParallel)
+			if err != nil {
+				return err
+			}
+			node.SetOutput(results)
+			return nil
+		},
+	}
+	reduceNode = &Node[T]{
+		Name:         name + "/reduce",
+		Dependencies: []*Node[T]{mapNode},
+		Processor: func(node IRuntimeNode, params T) error {
+			results, _ := InputOf[[]R](node, name)
+			return reducer(node, params, results)
+		},
+	}
+	return mapNode, reduceNode
+}
+
+// runMap 并行执行各子任务，返回按子任务下标排列的输出
+func runMap[T, E, R any](node IRuntimeNode, params T, list []E, fn func(node IRuntimeNode, params T, item E) (R, error), maxParallel int) ([]R, error) {
+	parallel := maxParallel
+	if parallel <= 0 || parallel > len(list) {
+		parallel = len(list)
+	}
+	results := make([]R, len(list))
+	errs := make([]error, len(list))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, item := range list {
+		select {
+		case slots <- struct{}{}:
+		case <-node.Context().Done():
+			errs[i] = context.Cause(node.Context())
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				if e := recover(); e != nil {
+					errs[i] = recovered(node, e)
+				}
+				<-slots
+				wg.Done()
+			}()
+			results[i], errs[i] = fn(node, params, item)
+		}()
+	}
+	wg.Wait()
+	mapErr := &MapErr{NodeName: node.GetName()}
+	for i, err := range errs {
+		if err != nil {
+			mapErr.Items = append(mapErr.Items, i)
+			mapErr.Errs = append(mapErr.Errs, err)
+		}
+	}
+	if len(mapErr.Items) == 0 {
+		return results, nil
+	}
+	return results, mapErr
+}
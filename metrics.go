@@ -0,0 +1,108 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"hash/fnv"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrencySample 并发度采样点，在每个 processor 开始或结束执行时记录一次
+type ConcurrencySample struct {
+	Time    time.Time
+	Running int // 该时刻正在执行 processor 的节点数
+}
+
+// concurrencyGauge 基于事件记录一次运行中的并发度变化
+type concurrencyGauge struct {
+	mu        sync.Mutex
+	running   int
+	peak      int
+	noSamples bool // 未被采样的运行只记录峰值
+	samples   []ConcurrencySample
+}
+
+func (g *concurrencyGauge) inc() {
+	g.mu.Lock()
+	g.running++
+	if g.running > g.peak {
+		g.peak = g.running
+	}
+	if !g.noSamples {
+		g.samples = append(g.samples, ConcurrencySample{Time: time.Now(), Running: g.running})
+	}
+	g.mu.Unlock()
+}
+
+func (g *concurrencyGauge) dec() {
+	g.mu.Lock()
+	g.running--
+	if !g.noSamples {
+		g.samples = append(g.samples, ConcurrencySample{Time: time.Now(), Running: g.running})
+	}
+	g.mu.Unlock()
+}
+
+func (g *concurrencyGauge) snapshot() (int, []ConcurrencySample) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.peak, slices.Clone(g.samples)
+}
+
+// sampled 按运行 ID 确定性地决定是否采样，rate 小于或等于0或大于等于1时总是采样
+func sampled(runID string, rate float64) bool {
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(runID))
+	return float64(h.Sum64()) < rate*math.MaxUint64
+}
+
+// AvgConcurrency 运行期间按时间加权的平均并发度，未被采样的运行返回0
+func (result *RunResult) AvgConcurrency() float64 {
+	if result.Cost <= 0 {
+		return 0
+	}
+	var sum float64
+	for i, sample := range result.Concurrency {
+		end := result.End
+		if i+1 < len(result.Concurrency) {
+			end = result.Concurrency[i+1].Time
+		}
+		if end.After(result.End) {
+			end = result.End
+		}
+		if end.After(sample.Time) {
+			sum += float64(sample.Running) * float64(end.Sub(sample.Time))
+		}
+	}
+	return sum / float64(result.Cost)
+}
+
+// peakGauge 记录当前值与峰值的计数器
+type peakGauge struct {
+	cur  atomic.Int64
+	peak atomic.Int64
+}
+
+func (g *peakGauge) inc() {
+	cur := g.cur.Add(1)
+	for {
+		peak := g.peak.Load()
+		if cur <= peak || g.peak.CompareAndSwap(peak, cur) {
+			return
+		}
+	}
+}
+
+func (g *peakGauge) dec() {
+	g.cur.Add(-1)
+}
@@ -0,0 +1,53 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+// Middleware processor 中间件，包裹图内每个节点的 processor（包括金丝雀实现），用于日志、追踪、指标、鉴权等横切关注点
+type Middleware[T any] func(next Processor[T]) Processor[T]
+
+// MiddlewareAfterRunErr 图已开始运行后再通过 Use 注册中间件
+const MiddlewareAfterRunErr = strErr("middlewares must be registered before the first run")
+
+// Use 注册 processor 中间件，位于 BuildOptions.Middlewares 与先前注册的中间件的内层。
+// 需在图首次运行之前调用，之后调用不生效并返回 MiddlewareAfterRunErr
+func (dag *DAG[T]) Use(mws ...Middleware[T]) error {
+	dag.mwMu.Lock()
+	defer dag.mwMu.Unlock()
+	if dag.mwSealed.Load() {
+		return MiddlewareAfterRunErr
+	}
+	dag.middlewares = append(dag.middlewares, mws...)
+	return nil
+}
+
+// sealMiddlewares 首次运行时按注册顺序包裹各节点的 processor 与金丝雀实现，之后不再重复包裹，也不可再注册中间件
+func (dag *DAG[T]) sealMiddlewares() {
+	if dag.mwSealed.Load() {
+		return
+	}
+	dag.mwMu.Lock()
+	defer dag.mwMu.Unlock()
+	if dag.mwSealed.Load() {
+		return
+	}
+	mws := dag.middlewares
+	wrap := func(processor Processor[T]) Processor[T] {
+		if processor == nil {
+			return nil
+		}
+		for i := len(mws) - 1; i >= 0; i-- {
+			processor = mws[i](processor)
+		}
+		return processor
+	}
+	if len(mws) > 0 {
+		for _, node := range dag.metaNodes {
+			node.processor = wrap(node.processor)
+			node.canary = wrap(node.canary)
+		}
+	}
+	dag.mwSealed.Store(true)
+}
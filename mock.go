@@ -0,0 +1,40 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"time"
+)
+
+// MockOptions 模拟运行的配置。模拟运行时所有 processor 被替换为等待固定耗时后成功的函数，
+// Load 与 Persist 不会被调用，拓扑、钩子函数与导出保持不变，可用于演示或压测调度层而不访问真实后端
+type MockOptions struct {
+	Default time.Duration            // 未在 Latency 中配置的节点的模拟耗时
+	Latency map[string]time.Duration // 按节点名称配置的模拟耗时
+}
+
+// latency 获取节点的模拟耗时
+func (m *MockOptions) latency(name string) time.Duration {
+	if d, ok := m.Latency[name]; ok {
+		return d
+	}
+	return m.Default
+}
+
+// mockProcess 等待模拟耗时后返回成功，节点超时或运行被取消时提前返回
+func (node *runtimeNode[T]) mockProcess() error {
+	d := node.ctx.mock.latency(node.name)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-node.Context().Done():
+	}
+	return nil
+}
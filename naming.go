@@ -0,0 +1,52 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// NameFunc 为未设置 Name 的节点生成名称，idx 为节点在图内的下标，processor 为节点的 Processor（可能为 nil）
+type NameFunc func(idx int, processor any) string
+
+// defaultName 使用构建配置中的命名函数生成节点名称，未设置时使用 NameNoname
+func (b *dagBuilder[T]) defaultName(idx int, processor any) string {
+	if b.opts.NameFunc == nil {
+		return NameNoname(idx, processor)
+	}
+	return b.opts.NameFunc(idx, processor)
+}
+
+// NameNoname 默认的命名函数，所有未命名节点均命名为 "noname"
+func NameNoname(int, any) string {
+	return "noname"
+}
+
+// NameByIndex 以节点下标命名，格式为 node-<下标>
+func NameByIndex(idx int, _ any) string {
+	return "node-" + strconv.Itoa(idx)
+}
+
+// NameByProcessor 以 processor 的函数名（去掉包路径）命名，如 "main.fetchOrders"，匿名函数形如 "main.main.func1"，
+// processor 为 nil 时退化为 NameByIndex
+func NameByProcessor(idx int, processor any) string {
+	v := reflect.ValueOf(processor)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return NameByIndex(idx, processor)
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return NameByIndex(idx, processor)
+	}
+	name := fn.Name()
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,45 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"sync"
+)
+
+// NoProgressPolicy 所有根节点均失败时的处理策略
+type NoProgressPolicy int
+
+const (
+	// NoProgressContinue 默认策略，与其它情况一致，继续调度可以运行的节点（如弱依赖子节点）
+	NoProgressContinue NoProgressPolicy = iota
+	// NoProgressFail 立即终止运行，RunResult.Err() 可通过 errors.As 获取汇总了根节点错误的 *NoProgressErr
+	NoProgressFail
+)
+
+// noProgressTracker 记录失败的根节点，所有根节点均失败时终止运行
+type noProgressTracker struct {
+	mu        sync.Mutex
+	remaining int
+	roots     []string
+	errs      []error
+}
+
+func newNoProgressTracker(rootCnt int) *noProgressTracker {
+	return &noProgressTracker{remaining: rootCnt}
+}
+
+// onRootFailed 记录失败的根节点，所有根节点都失败时返回汇总的错误
+func (t *noProgressTracker) onRootFailed(name string, err error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.roots = append(t.roots, name)
+	t.errs = append(t.errs, err)
+	t.remaining--
+	if t.remaining > 0 {
+		return nil
+	}
+	return &NoProgressErr{Roots: t.roots, Errs: t.errs}
+}
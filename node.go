@@ -13,33 +13,133 @@ type Processor[T any] func(node IRuntimeNode, params T) error
 
 type NodeHookFunc[T any] func(node IRuntimeNode, params T)
 
+// RetryHookFunc 重试钩子函数，attempt 为刚失败的运行次数，err 为该次运行的错误
+type RetryHookFunc[T any] func(node IRuntimeNode, params T, attempt uint, err error)
+
+// NodeResultHookFunc 携带节点运行结果快照的钩子函数
+type NodeResultHookFunc[T any] func(node IRuntimeNode, params T, result *NodeResult)
+
 type Node[T any] struct {
-	// Name 节点名称，仅在err里展示用，建议 Name 保持唯一性
+	// Name 节点名称，仅在err里展示用，建议 Name 保持唯一性，为空时由 BuildOptions.NameFunc 生成
 	Name string
-	// Processor 节点方法，返回 nil 表示成功，返回 err 表示失败。超时后将无视该函数的返回值，并视为返回 TimeoutErr
+	// Config 节点的静态配置，可在 processor 中通过 IRuntimeNode.Config 获取，使同一个 processor 在不同节点上按配置执行
+	Config map[string]string
+	// RequiredConfig processor 依赖的配置项，Validate 会报告 Config 中缺失的配置项，使配置错误在加载图时而不是运行中暴露。
+	// 复用的 processor 可与其所需的配置项一起提供
+	RequiredConfig []string
+	// Meta 节点的描述性元数据（如负责人、运行手册链接、团队标签），不影响执行，会出现在 Mermaid 导出、图定义与运行结果中
+	Meta map[string]any
+	// Owner 节点的负责团队或负责人，AlertChannel 节点失败时的告警渠道（如群组、值班表），
+	// 会出现在 NodeResult、node.finished 事件与图定义中，供告警系统在多团队共享的图中路由通知
+	Owner        string
+	AlertChannel string
+	// Tags 节点的标签，配合 RunOptions.IncludeTags 与 ExcludeTags 让同一个图服务多个产品变体
+	Tags []string
+	// Processor 节点方法，返回 nil 表示成功，返回 err 表示失败。超时后将无视该函数的返回值，并视为返回 *TimeoutError（满足 errors.Is(err, TimeoutErr)）
 	Processor Processor[T]
+	// Canary 节点的金丝雀实现，在按 RunOptions.CanaryRate 选中的运行中替代 Processor 执行，所用的实现记录在 NodeResult.Variant 中，为 nil 时总是执行 Processor
+	Canary Processor[T]
+	// Inline 在触发该节点的协程（上游节点或调度根节点的协程）内直接执行，而不是提交到协程池，适用于耗时极短的汇合、转换节点。
+	// Processor 为 nil 的节点总是内联执行
+	Inline bool
+	// Policy 引用的策略预设名称（见 RegisterPolicy），节点未设置的超时、重试相关字段由预设填充，为空时表示不使用预设
+	Policy string
+	// PolicyOverrides 节点显式设置的布尔字段（如 PolicyRetryOnPanic），即使节点上的值为 false 也不被预设填充，使节点可以关闭预设开启的选项
+	PolicyOverrides PolicyField
 	// LocalTimeout 本地超时时间，在节点开始执行时开始计时，小于或等于0时表示无超时时
 	LocalTimeout time.Duration
+	// InheritTimeout 未设置 LocalTimeout 且运行存在截止时间（如 RunContext 传入带 deadline 的 ctx）时，
+	// 节点以最慢的强依赖完成时（根节点为运行开始时）的剩余运行时间除以其到叶子节点的最长强依赖链长度（包含自身）作为本地超时时间，
+	// 使深链路自动分摊整体截止时间，排队等待不占用节点分得的时间
+	InheritTimeout bool
 	// TotalTimeout 全局超时时间，在图开始执行时开始计时，小于或等于0时表示无超时时间
 	TotalTimeout time.Duration
+	// Condition 节点执行前的判断条件，返回 false 时节点被标记为 Skipped（而非 Failed）且不执行，为 nil 时总是执行。
+	// 可用于按数据选择分支，而无需构建多个图
+	Condition func(node IRuntimeNode, params T) bool
+	// SkipPolicy 强依赖被跳过时该节点的处理策略，默认同样被跳过。弱依赖被跳过时总是视为已满足
+	SkipPolicy SkipPolicy
 	// Dependencies 强依赖，依赖节点若出现 err（超时也是一种 err），当前节点不会运行
 	Dependencies []*Node[T]
+	// DependencyConditions 强依赖的边条件，依赖节点成功后以 params 求值，返回 false 时该依赖视为被跳过，
+	// 当前节点按 SkipPolicy 处理，发生 panic 时当前节点以 *PanicErr 失败，可配合 SkipAsSatisfied 构建分支与汇合的拓扑。通常通过 AddDependencyIf 设置
+	DependencyConditions map[*Node[T]]func(params T) bool
 	// WeakDependencies 弱依赖，依赖节点若失败或超时，当前节点继续运行
 	WeakDependencies []*Node[T]
+	// EarlyStart 弱依赖仅表示先后关系而不阻塞执行：强依赖均结束后（没有强依赖时与根节点一同）即开始执行，
+	// 进行中的弱依赖可通过 IRuntimeNode.AwaitDep 在限定时间内等待。开始执行后才失败的弱依赖不计入 NodeResult.Degraded
+	EarlyStart bool
+	// Weight 节点的预估权重（如预估耗时），用于关键路径分析与优先级调度，小于或等于0时视为0
+	Weight float64
+	// Priority 节点优先级，值越大越先调度，仅在 RunOptions.DispatchOrder 为 DispatchByPriority 时生效
+	Priority int
+	// MaxConcurrent 该节点在同一个图的所有并发运行中最多同时执行的数量，小于或等于0时表示不限制
+	MaxConcurrent int
+	// Exclusive 节点在所有共享 RunOptions.Leases 的进程中同一时刻最多执行一个，执行前获取以图名称（见 BuildOptions.Name）与节点名称为键的租约，执行期间自动续约，
+	// 持有者崩溃时租约到期后由其他进程接管，续约失败时节点以 LeaseLostErr 失败。未设置 RunOptions.Leases 时不生效
+	Exclusive bool
+	// NoSteal 节点以 SubmitPinned 提交到协程池，不会被同组的其他协程池窃取（见 StealGroup），
+	// 适用于依赖协程池 worker 上准备的资源（见 Pool.SetWorkerHooks）的节点
+	NoSteal bool
+	// Resources 节点执行时需要持有的外部资源键，各资源的并发持有数由 RunOptions.Resources 限制
+	Resources []string
 	// MaxAttempts 最大重试次数，小于1时被视为1
 	MaxAttempts uint
+	// RetryQueueLimit 协程池（需实现 IQueuedPool）排队任务数超过该值时推迟重试，避免过载时重试与其他节点的首次执行争抢 worker。
+	// 推迟期间节点归还并发名额且不占用 worker，排队任务数下降后重新提交，小于或等于0时表示不限制
+	RetryQueueLimit int
+	// RetryQueueFail 排队任务数超过 RetryQueueLimit 时直接以最后一次的错误失败，而不是等待排队任务减少后再重试
+	RetryQueueFail bool
+	// RetryOnPanic processor 发生 panic 时是否继续重试，默认不重试，直接视为节点失败
+	RetryOnPanic bool
+	// RetryIf 判断错误是否可重试，返回 false 时（如参数校验失败等永久性错误）不再重试，直接以该错误失败，为 nil 时所有错误都会重试。
+	// processor 发生 panic 时先由 RetryOnPanic 判断
+	RetryIf func(err error) bool
+	// QuarantineAfter 节点在连续多少次运行中失败后被隔离，隔离期间节点不执行，直接以 Skipped 结束，NodeResult.Err 为 QuarantinedErr，下游按 SkipPolicy 处理，为0时表示不隔离。
+	// 适用于弱依赖的可选节点，避免已知故障的节点拖慢每一次运行
+	QuarantineAfter uint
+	// QuarantineCooldown 隔离时长，到期后允许再次执行，小于或等于0时表示一直隔离到调用 DAG.ResetQuarantine
+	QuarantineCooldown time.Duration
 	// BackoffFunc 退避策略，即重试之间等待的时间间隔
 	BackoffFunc BackoffFunc
+	// SLA 节点的服务等级目标，违约记录在 RunResult.SLABreaches 中
+	SLA NodeSLA
+	// Load 在执行 processor 之前尝试从外部缓存加载节点结果（通常写入 params），返回 true 时跳过执行并视为成功，返回 err 时视为未命中
+	Load func(node IRuntimeNode, params T) (bool, error)
+	// Persist 在 processor 执行成功后保存节点结果到外部缓存，返回的 err 记录在 NodeResult.PersistErr 中，不影响节点状态
+	Persist func(node IRuntimeNode, params T) error
+	// Cache 节点结果的记忆化缓存，与 CacheKey 同时设置时生效，命中时跳过执行并恢复节点的输出，NodeResult.CacheHit 为 true。
+	// 只缓存通过 IRuntimeNode.SetOutput 设置的输出，未设置输出（如把结果写入 params）的节点总是执行。可在多次运行、多个图间共享
+	Cache Cache
+	// CacheKey 根据 params 计算缓存键，实际的键为 节点名称/CacheKey(params)，相同的输入应返回相同的键。
+	// 同时记录在 NodeResult.InputKey 中，作为 RunWarm 判断节点输入是否变化的依据
+	CacheKey func(params T) string
+	// OnStart 节点每次开始执行 processor 前的钩子函数，可通过 IRuntimeNode.GetAttempts 获取本次的运行次数，
+	// 第一次调用时节点刚从等待进入执行，可结合 NodeResult.QueueWait 记录调度延迟。计入节点的超时时间
+	OnStart NodeHookFunc[T]
+	// OnRetry 节点某次运行失败且将要重试时、退避等待之前的钩子函数，可用于记录日志、上报指标或调整退避状态
+	OnRetry RetryHookFunc[T]
 	// 节点运行成功的钩子函数
 	OnSuccess NodeHookFunc[T]
 	// 节点运行失败的钩子函数
 	OnFailure NodeHookFunc[T]
+	// 节点运行结束（成功或失败）的钩子函数，在 OnSuccess 与 OnFailure 之后调用，result 为节点结束时的结果快照
+	OnFinish NodeResultHookFunc[T]
 }
 
 func (node *Node[T]) AddDependency(deps ...*Node[T]) {
 	node.Dependencies = append(node.Dependencies, deps...)
 }
 
+// AddDependencyIf 添加带条件的强依赖，仅在 dep 成功且 cond 返回 true 时该依赖才视为已满足
+func (node *Node[T]) AddDependencyIf(dep *Node[T], cond func(params T) bool) {
+	node.Dependencies = append(node.Dependencies, dep)
+	if node.DependencyConditions == nil {
+		node.DependencyConditions = make(map[*Node[T]]func(params T) bool)
+	}
+	node.DependencyConditions[dep] = cond
+}
+
 func (node *Node[T]) AddWeakDependency(weekDeps ...*Node[T]) {
 	node.WeakDependencies = append(node.WeakDependencies, weekDeps...)
 }
@@ -5,38 +5,148 @@
 
 package easydag
 
-import "time"
+import (
+	"maps"
+	"slices"
+	"time"
+)
 
 // nodeMetadata 记录Node的元信息，作用如下：
 // 1.避免创建dag后节点信息被用户修改，造成不符合预期的结果
 // 2.把依赖节点的指针换为下标，储存dag时便可以把map换为slice，减少内存占用，加快查询速度
 type nodeMetadata[T any] struct {
-	name         string
-	processor    Processor[T]
-	localTimeout time.Duration
-	totalTimeout time.Duration
-	depCnt       int32
-	children     []int
-	weakChildren []int
-	maxAttempts  uint
-	backoffFunc  BackoffFunc
-	onSuccess    NodeHookFunc[T]
-	onFailure    NodeHookFunc[T]
+	name            string
+	config          map[string]string
+	required        []string // 必需的配置项
+	meta            map[string]any
+	owner           string
+	alertChannel    string
+	tags            []string // 排序去重后的标签
+	pinned          bool     // 是否不可被工作窃取，见 Node.NoSteal
+	policy          string
+	policyOverrides PolicyField
+	processor       Processor[T]
+	canary          Processor[T]
+	inline          bool // 是否在触发该节点的协程内直接执行
+	anchor          bool // 是否为锚点，见 NewAnchorNode
+	condition       func(node IRuntimeNode, params T) bool
+	skipPolicy      SkipPolicy
+	localTimeout    time.Duration
+	inherit         bool  // 是否从运行截止时间继承本地超时时间
+	chainLen        int64 // 到叶子节点的最长强依赖链长度（包含自身），构建图时计算
+	totalTimeout    time.Duration
+	weight          float64
+	priority        int
+	slots           chan struct{} // 节点在所有运行间共享的并发执行名额
+	resources       []string      // 排序去重后的资源键，按顺序获取以避免死锁
+	exclusive       bool          // 是否需要持有租约才能执行
+	depCnt          int32
+	strongCnt       int32                       // 强依赖数
+	earlyStart      bool                        // 是否不等待弱依赖即开始执行
+	depConds        map[int]func(params T) bool // 强依赖下标 -> 边条件
+	deps            []int                       // 强依赖与弱依赖的下标
+	children        []int
+	weakChildren    []int
+	maxAttempts     uint
+	retryOnPanic    bool
+	retryIf         func(err error) bool
+	// retryQueueLimit、retryQueueFail 协程池过载时推迟或放弃重试
+	retryQueueLimit int
+	retryQueueFail  bool
+	backoffFunc     BackoffFunc
+	quarantine      *quarantine // 为 nil 时表示不隔离
+	sla             NodeSLA
+	slaStats        slaStats
+	load            func(node IRuntimeNode, params T) (bool, error)
+	persist         func(node IRuntimeNode, params T) error
+	cache           Cache
+	cacheKey        func(params T) string
+	onStart         NodeHookFunc[T]
+	onRetry         RetryHookFunc[T]
+	onSuccess       NodeHookFunc[T]
+	onFailure       NodeHookFunc[T]
+	onFinish        NodeResultHookFunc[T]
+	checksum        uint64 // 构建时用户节点配置的校验和，用于发现构建后对节点的修改
 }
 
 func newNodeMetadata[T any](node *Node[T]) *nodeMetadata[T] {
 	metaData := &nodeMetadata[T]{
-		name:         node.Name,
-		processor:    node.Processor,
-		localTimeout: node.LocalTimeout,
-		totalTimeout: node.TotalTimeout,
-		maxAttempts:  node.MaxAttempts,
-		backoffFunc:  node.BackoffFunc,
-		onSuccess:    node.OnSuccess,
-		onFailure:    node.OnFailure,
-	}
-	if metaData.name == "" {
-		metaData.name = "noname"
+		name:            node.Name,
+		config:          maps.Clone(node.Config),
+		required:        slices.Clone(node.RequiredConfig),
+		meta:            maps.Clone(node.Meta),
+		owner:           node.Owner,
+		alertChannel:    node.AlertChannel,
+		policy:          node.Policy,
+		processor:       node.Processor,
+		canary:          node.Canary,
+		inline:          node.Inline || node.Processor == nil,
+		earlyStart:      node.EarlyStart,
+		anchor:          isAnchor(node),
+		condition:       node.Condition,
+		skipPolicy:      node.SkipPolicy,
+		localTimeout:    node.LocalTimeout,
+		inherit:         node.InheritTimeout,
+		totalTimeout:    node.TotalTimeout,
+		weight:          node.Weight,
+		exclusive:       node.Exclusive,
+		pinned:          node.NoSteal,
+		priority:        node.Priority,
+		maxAttempts:     node.MaxAttempts,
+		retryOnPanic:    node.RetryOnPanic,
+		policyOverrides: node.PolicyOverrides,
+		retryIf:         node.RetryIf,
+		retryQueueLimit: node.RetryQueueLimit,
+		retryQueueFail:  node.RetryQueueFail,
+		backoffFunc:     node.BackoffFunc,
+		quarantine:      newQuarantine(node.QuarantineAfter, node.QuarantineCooldown),
+		sla:             node.SLA,
+		load:            node.Load,
+		persist:         node.Persist,
+		cache:           node.Cache,
+		cacheKey:        node.CacheKey,
+		onStart:         node.OnStart,
+		onRetry:         node.OnRetry,
+		onSuccess:       node.OnSuccess,
+		onFailure:       node.OnFailure,
+		onFinish:        node.OnFinish,
+	}
+	if metaData.weight < 0 {
+		metaData.weight = 0
+	}
+	if len(node.Tags) > 0 {
+		metaData.tags = slices.Compact(slices.Sorted(slices.Values(node.Tags)))
+	}
+	if len(node.Resources) > 0 {
+		metaData.resources = slices.Compact(slices.Sorted(slices.Values(node.Resources)))
+	}
+	if node.MaxConcurrent > 0 {
+		metaData.slots = make(chan struct{}, node.MaxConcurrent)
 	}
 	return metaData
 }
+
+// isAnchor 节点是否为锚点：没有 processor，且未设置 Condition、钩子函数与执行相关的配置
+func isAnchor[T any](node *Node[T]) bool {
+	return node.Processor == nil && node.Canary == nil && node.Condition == nil &&
+		node.OnStart == nil && node.OnRetry == nil && node.OnSuccess == nil && node.OnFailure == nil && node.OnFinish == nil &&
+		node.Load == nil && node.Cache == nil && len(node.Resources) == 0 && node.MaxConcurrent <= 0 && !node.Exclusive
+}
+
+// excludedBy 节点是否被运行配置的标签过滤
+func (metaData *nodeMetadata[T]) excludedBy(include, exclude []string) bool {
+	for _, tag := range metaData.tags {
+		if slices.Contains(exclude, tag) {
+			return true
+		}
+	}
+	if len(include) == 0 || len(metaData.tags) == 0 {
+		return false
+	}
+	for _, tag := range metaData.tags {
+		if slices.Contains(include, tag) {
+			return false
+		}
+	}
+	return true
+}
@@ -6,13 +6,45 @@
 package easydag
 
 import (
+	"errors"
 	"time"
 )
 
 type NodeResult struct {
-	Status   int
-	Err      error
-	Begin    time.Time
-	Cost     time.Duration // 节点执行耗时，
-	Attempts uint
+	Name         string
+	Meta         map[string]any // 节点的元数据，与图共享，不可修改
+	Tags         []string       // 节点的标签，与图共享，不可修改
+	Owner        string         // 节点的负责人，见 Node.Owner
+	AlertChannel string         // 节点的告警渠道
+	Status       NodeStatus
+	Err          error
+	Output       any // 节点通过 IRuntimeNode.SetOutput 设置的输出
+	Begin        time.Time
+	Cost         time.Duration // 节点执行耗时，
+	QueueWait    time.Duration // 节点从就绪到开始执行的等待时间，主要为协程池排队时间
+	FanInWait    time.Duration // 有多个依赖的节点从第一个依赖完成到所有依赖完成的时间，可用于分析汇合节点的等待分布
+	Attempts     uint
+	Variant      string       // 设置了 Node.Canary 的节点本次执行的实现，为 VariantBaseline 或 VariantCanary，未执行时为空
+	Panicked     bool         // 节点是否因 processor panic 而失败
+	TimeoutBy    error        // 节点超时时触发的截止时间，为 LocalTimeoutErr 或 TotalTimeoutErr，未超时时为 nil
+	SubRuns      []*RunResult // 节点内运行的子图结果
+	Degraded     bool         // 是否在有弱依赖失败的情况下运行
+	Reused       bool         // 结果是否复用自历史运行，复用时节点未被执行
+	InputKey     string       // 节点设置了 CacheKey 时，本次运行的输入键
+	CacheHit     bool         // 是否命中外部缓存（Load）或记忆化缓存（Cache）而跳过执行
+	PersistErr   error        // 保存结果到外部缓存时的错误
+}
+
+const (
+	VariantBaseline = "baseline" // 执行了 Node.Processor
+	VariantCanary   = "canary"   // 执行了 Node.Canary
+)
+
+// timeoutBy 返回超时错误触发的截止时间，不是超时错误时返回 nil
+func timeoutBy(err error) error {
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr.Deadline
+	}
+	return nil
 }
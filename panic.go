@@ -0,0 +1,89 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicHandler processor、Condition、边条件或 MapNode 的映射函数发生 panic 时的回调，可用于上报崩溃与堆栈，可能被并发调用。
+// 回调本身不影响节点结果，节点仍以 *PanicErr 失败
+type PanicHandler func(node IRuntimeNode, value any, stack []byte)
+
+// PanicScope processor 发生 panic 时影响的范围
+type PanicScope int
+
+const (
+	// PanicScopeDefault 与其他错误相同，强依赖的下游节点不会执行，设置了 RunOptions.FailFast 时终止运行
+	PanicScopeDefault PanicScope = iota
+	// PanicScopeBranch 仅影响所在分支，强依赖的下游节点（包括间接下游）以 Skipped 结束，错误为 *BranchPanicErr，
+	// 即使设置了 RunOptions.FailFast 也不终止运行，其余分支继续执行，可通过 RunResult.Partial 判断运行是否部分失败
+	PanicScopeBranch
+	// PanicScopeRun 终止运行，即使未设置 RunOptions.FailFast，未开始执行的节点被标记为 Cancelled，错误为 *FailFastErr
+	PanicScopeRun
+)
+
+// BranchPanicErr 节点因上游节点发生 panic 而被跳过，见 PanicScopeBranch
+type BranchPanicErr struct {
+	NodeName string // 发生 panic 的节点
+}
+
+func (e *BranchPanicErr) Error() string {
+	return fmt.Sprintf("skipped: upstream node %s panicked", e.NodeName)
+}
+
+// abortOnFailure 判断失败的节点是否需要终止运行
+func (node *runtimeNode[T]) abortOnFailure() bool {
+	var panicErr *PanicErr
+	if !errors.As(node.err, &panicErr) {
+		return node.ctx.failFast
+	}
+	switch node.ctx.panicScope {
+	case PanicScopeBranch:
+		return false
+	case PanicScopeRun:
+		return true
+	default:
+		return node.ctx.failFast
+	}
+}
+
+// branchPanic 返回以 PanicScopeBranch 隔离时需要传递给强依赖下游节点的错误，不需要传递时返回 nil
+func (node *runtimeNode[T]) branchPanic() *BranchPanicErr {
+	if node.ctx.panicScope != PanicScopeBranch {
+		return nil
+	}
+	if err := node.upstreamPanic.Load(); err != nil {
+		return err
+	}
+	var panicErr *PanicErr
+	if node.status.Load() == Failed && errors.As(node.err, &panicErr) {
+		return &BranchPanicErr{NodeName: node.name}
+	}
+	return nil
+}
+
+// panicReporter 可回调 PanicHandler 的运行时节点
+type panicReporter interface {
+	reportPanic(value any, stack []byte)
+}
+
+// recovered 将 recover 得到的值转换为带有堆栈的 *PanicErr，并回调本次运行的 PanicHandler，需在 recover 所在的 defer 中调用
+func recovered(node IRuntimeNode, value any) *PanicErr {
+	stack := debug.Stack()
+	if reporter, ok := node.(panicReporter); ok {
+		reporter.reportPanic(value, stack)
+	}
+	return &PanicErr{NodeName: node.GetName(), Value: value, Stack: stack}
+}
+
+func (node *runtimeNode[T]) reportPanic(value any, stack []byte) {
+	if node.ctx.panicHandler != nil {
+		node.ctx.panicHandler(node, value, stack)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"sync"
+	"time"
+)
+
+// Policy 超时与重试策略预设，节点通过 Node.Policy 引用，节点自身设置的字段优先于预设
+type Policy struct {
+	LocalTimeout time.Duration
+	TotalTimeout time.Duration
+	MaxAttempts  uint
+	RetryOnPanic bool
+	RetryIf      func(err error) bool
+	BackoffFunc  BackoffFunc
+	// RetryQueueLimit、RetryQueueFail 同 Node 中的同名字段
+	RetryQueueLimit int
+	RetryQueueFail  bool
+}
+
+// PolicyField Policy 中的布尔字段，可按位组合，用于 Node.PolicyOverrides
+type PolicyField uint8
+
+const (
+	PolicyRetryOnPanic PolicyField = 1 << iota
+	PolicyRetryQueueFail
+)
+
+var (
+	policiesMu sync.RWMutex
+	policies   = make(map[string]Policy)
+)
+
+// RegisterPolicy 注册或覆盖名为 name 的策略预设（如 "fast-rpc"、"slow-batch"），
+// 预设在构建图时展开，之后修改预设不会影响已构建的图
+func RegisterPolicy(name string, policy Policy) {
+	policiesMu.Lock()
+	policies[name] = policy
+	policiesMu.Unlock()
+}
+
+// LookupPolicy 获取名为 name 的策略预设
+func LookupPolicy(name string) (Policy, bool) {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+	policy, ok := policies[name]
+	return policy, ok
+}
+
+// UnknownPolicyErr 节点引用了未注册的策略预设
+type UnknownPolicyErr struct {
+	NodeName string
+	Policy   string
+}
+
+func (e *UnknownPolicyErr) Error() string {
+	return "unknown policy " + e.Policy + " for node " + e.NodeName
+}
+
+func (e *UnknownPolicyErr) NodeNames() []string {
+	return []string{e.NodeName}
+}
+
+// applyPolicy 用策略预设填充节点未设置的字段，布尔字段为 false 且未在 PolicyOverrides 中声明时视为未设置
+func (metaData *nodeMetadata[T]) applyPolicy(policy Policy) {
+	overridden := func(field PolicyField) bool {
+		return metaData.policyOverrides&field != 0
+	}
+	if metaData.localTimeout <= 0 {
+		metaData.localTimeout = policy.LocalTimeout
+	}
+	if metaData.totalTimeout <= 0 {
+		metaData.totalTimeout = policy.TotalTimeout
+	}
+	if metaData.maxAttempts == 0 {
+		metaData.maxAttempts = policy.MaxAttempts
+	}
+	if !metaData.retryOnPanic && !overridden(PolicyRetryOnPanic) {
+		metaData.retryOnPanic = policy.RetryOnPanic
+	}
+	if metaData.retryIf == nil {
+		metaData.retryIf = policy.RetryIf
+	}
+	if metaData.backoffFunc == nil {
+		metaData.backoffFunc = policy.BackoffFunc
+	}
+	if metaData.retryQueueLimit <= 0 {
+		metaData.retryQueueLimit = policy.RetryQueueLimit
+		if !overridden(PolicyRetryQueueFail) {
+			metaData.retryQueueFail = metaData.retryQueueFail || policy.RetryQueueFail
+		}
+	}
+}
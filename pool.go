@@ -7,67 +7,218 @@ package easydag
 
 import (
 	"sync"
+	"time"
 )
 
 type IPool interface {
 	Submit(func())
 }
 
+// IClassPool 支持按运行类别区分优先级的协程池，交互类任务优先于排队中的批处理任务执行
+type IClassPool interface {
+	IPool
+	SubmitWithClass(f func(), class RunClass)
+}
+
+// IQueuedPool 可查询排队任务数的协程池
+type IQueuedPool interface {
+	IPool
+	QueueLen() int
+}
+
 type Pool struct {
 	mu         sync.Mutex
-	head       *task
-	tail       *task
-	len        int
+	queues     [runClassCnt]taskQueue // 按运行类别划分的等待队列，下标越小优先级越高
 	maxWorkers int
+	sizer      *autoSizer // 为 nil 时表示 maxWorkers 固定
 	workers    int
+	observers  []PoolObserver
+	stealGroup *StealGroup // 为 nil 时表示不参与工作窃取
+	// workerInit、workerCleanup 在 worker 协程启动后、退出前于该协程内调用
+	workerInit    func()
+	workerCleanup func()
 }
 
 type task struct {
-	f    func()
-	next *task
+	f        func()
+	enqueued time.Time
+	pinned   bool // 是否不可被同组的其他协程池窃取
+	next     *task
 }
 
-func NewPool(maxWorkers int) *Pool {
+// taskQueue 带哨兵头节点的 FIFO 队列
+type taskQueue struct {
+	head *task
+	tail *task
+	len  int
+}
+
+func newTaskQueue() taskQueue {
 	t := &task{}
-	t.next = t
-	return &Pool{
-		maxWorkers: maxWorkers,
-		head:       t,
-		tail:       t,
+	return taskQueue{head: t, tail: t}
+}
+
+func (q *taskQueue) push(f func(), enqueued time.Time, pinned bool) {
+	newTail := &task{f: f, enqueued: enqueued, pinned: pinned}
+	q.tail.next = newTail
+	q.tail = newTail
+	q.len++
+}
+
+func (q *taskQueue) pop() (func(), time.Time) {
+	newHead := q.head.next
+	q.head = newHead
+	f := newHead.f
+	// 新的头节点成为哨兵，释放其持有的函数
+	newHead.f = nil
+	q.len--
+	return f, newHead.enqueued
+}
+
+func NewPool(maxWorkers int) *Pool {
+	p := &Pool{maxWorkers: maxWorkers}
+	for i := range p.queues {
+		p.queues[i] = newTaskQueue()
 	}
+	return p
+}
+
+// AddObserver 添加观测回调，需在提交任务之前添加
+func (p *Pool) AddObserver(observers ...PoolObserver) {
+	p.observers = append(p.observers, observers...)
+}
+
+// SetWorkerHooks 设置 worker 协程启动与退出时的回调，两者均在 worker 协程内执行，可用于准备协程独占的资源，
+// 如调用 runtime.LockOSThread 绑定线程。需在提交任务之前设置，参数为 nil 时表示不回调
+func (p *Pool) SetWorkerHooks(init, cleanup func()) {
+	p.workerInit = init
+	p.workerCleanup = cleanup
 }
 
 func (p *Pool) Submit(f func()) {
+	p.SubmitWithClass(f, ClassInteractive)
+}
+
+// SubmitWithClass 按运行类别提交任务，没有空闲 worker 时，交互类任务会排在所有批处理任务之前，但不会抢占正在执行的任务
+func (p *Pool) SubmitWithClass(f func(), class RunClass) {
+	p.submit(f, class, false)
+}
+
+func (p *Pool) submit(f func(), class RunClass, pinned bool) {
 	if f == nil {
 		return
 	}
+	if class < 0 || class >= runClassCnt {
+		class = ClassInteractive
+	}
 	p.mu.Lock()
+	if p.sizer != nil {
+		p.sizer.refresh(p)
+	}
 	if p.workers < p.maxWorkers {
 		p.workers++
+		workers := p.workers
 		p.mu.Unlock()
+		for _, o := range p.observers {
+			o.OnWorkerSpawn(workers)
+		}
 		go p.work(f)
 		return
 	}
-	newTail := &task{f: f}
-	p.tail.next = newTail
-	p.tail = newTail
-	p.len++
+	var enqueued time.Time
+	if len(p.observers) > 0 {
+		enqueued = time.Now()
+	}
+	p.queues[class].push(f, enqueued, pinned)
+	queueLen := p.queues[class].len
 	p.mu.Unlock()
+	for _, o := range p.observers {
+		o.OnEnqueue(class, queueLen)
+	}
+	if p.stealGroup != nil && !pinned {
+		p.stealGroup.wake(p)
+	}
+}
+
+// QueueLen 等待队列中的任务总数
+func (p *Pool) QueueLen() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for i := range p.queues {
+		n += p.queues[i].len
+	}
+	return n
 }
 
+// work worker 协程的主循环，f 为 nil 时表示由 StealGroup 唤醒，先查找可执行的任务
 func (p *Pool) work(f func()) {
+	if p.workerInit != nil {
+		p.workerInit()
+	}
+	var enqueued time.Time
+	// owner 任务所属的协程池，被窃取的任务计入原协程池的观测回调
+	owner := p
 	for {
-		f()
+		if f != nil {
+			owner.runTask(f, enqueued)
+		}
 		p.mu.Lock()
-		if p.len > 0 {
-			f = p.head.f
-			p.head = p.head.next
-			p.len--
+		f, enqueued = p.next()
+		owner = p
+		if f == nil && p.stealGroup != nil {
+			// 窃取期间不持有本池的锁，避免两个协程池互相窃取时死锁；窃取失败后需重新检查本池的队列
 			p.mu.Unlock()
-		} else {
+			var victim *Pool
+			if f, enqueued, victim = p.stealGroup.steal(p); victim != nil {
+				owner = victim
+				continue
+			}
+			p.mu.Lock()
+			f, enqueued = p.next()
+		}
+		if f == nil {
 			p.workers--
+			workers := p.workers
 			p.mu.Unlock()
+			if p.workerCleanup != nil {
+				p.workerCleanup()
+			}
+			for _, o := range p.observers {
+				o.OnWorkerRetire(workers)
+			}
 			return
 		}
+		p.mu.Unlock()
+	}
+}
+
+// next 按优先级取出本池排队中的下一个任务，队列为空时返回 nil，需持有锁
+func (p *Pool) next() (func(), time.Time) {
+	for i := range p.queues {
+		if p.queues[i].len > 0 {
+			return p.queues[i].pop()
+		}
+	}
+	return nil, time.Time{}
+}
+
+func (p *Pool) runTask(f func(), enqueued time.Time) {
+	if len(p.observers) == 0 {
+		f()
+		return
+	}
+	begin := time.Now()
+	var wait time.Duration
+	if !enqueued.IsZero() {
+		wait = begin.Sub(enqueued)
+	}
+	for _, o := range p.observers {
+		o.OnTaskStart(wait)
+	}
+	f()
+	cost := time.Since(begin)
+	for _, o := range p.observers {
+		o.OnTaskFinish(cost)
 	}
 }
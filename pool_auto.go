@@ -0,0 +1,94 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"runtime"
+	"time"
+)
+
+// Workload 协程池任务的负载类型，决定按 GOMAXPROCS 计算 worker 数量时使用的倍数
+type Workload int
+
+const (
+	WorkloadIO  Workload = iota // IO 密集型，processor 大部分时间在等待下游调用
+	WorkloadCPU                 // CPU 密集型，worker 数量超过 GOMAXPROCS 只会增加调度开销
+)
+
+const (
+	defaultIOMultiplier  = 16
+	defaultCPUMultiplier = 1
+)
+
+// PoolAutoOptions NewPoolAuto 的配置
+type PoolAutoOptions struct {
+	// Workload 负载类型，默认为 WorkloadIO
+	Workload Workload
+	// IOMultiplier、CPUMultiplier 两种负载类型下 worker 数量相对 GOMAXPROCS 的倍数，小于或等于0时分别为16与1
+	IOMultiplier  float64
+	CPUMultiplier float64
+	// MinWorkers、MaxWorkers worker 数量的上下限，小于或等于0时表示不限制，计算结果至少为1
+	MinWorkers int
+	MaxWorkers int
+	// Refresh 重新读取 GOMAXPROCS 的最小间隔，在提交任务时按需检查，小于或等于0时仅在创建时计算一次。
+	// GOMAXPROCS 可能在运行期间被 runtime.GOMAXPROCS 或感知 cgroup 配额的库调整
+	Refresh time.Duration
+}
+
+// autoSizer 按 GOMAXPROCS 计算协程池的 worker 数量
+type autoSizer struct {
+	opts    PoolAutoOptions
+	checked time.Time
+}
+
+// NewPoolAuto 按 GOMAXPROCS 与负载类型创建协程池，避免硬编码 worker 数量
+func NewPoolAuto(opts PoolAutoOptions) *Pool {
+	sizer := &autoSizer{opts: opts, checked: time.Now()}
+	p := NewPool(sizer.size())
+	if opts.Refresh > 0 {
+		p.sizer = sizer
+	}
+	return p
+}
+
+// size 按当前的 GOMAXPROCS 计算 worker 数量
+func (s *autoSizer) size() int {
+	multiplier := s.opts.IOMultiplier
+	if multiplier <= 0 {
+		multiplier = defaultIOMultiplier
+	}
+	if s.opts.Workload == WorkloadCPU {
+		multiplier = s.opts.CPUMultiplier
+		if multiplier <= 0 {
+			multiplier = defaultCPUMultiplier
+		}
+	}
+	workers := int(float64(runtime.GOMAXPROCS(0)) * multiplier)
+	if s.opts.MaxWorkers > 0 && workers > s.opts.MaxWorkers {
+		workers = s.opts.MaxWorkers
+	}
+	if s.opts.MinWorkers > 0 && workers < s.opts.MinWorkers {
+		workers = s.opts.MinWorkers
+	}
+	return max(workers, 1)
+}
+
+// refresh 距上次计算超过 Refresh 时重新计算 worker 数量，需持有协程池的锁
+func (s *autoSizer) refresh(p *Pool) {
+	now := time.Now()
+	if now.Sub(s.checked) < s.opts.Refresh {
+		return
+	}
+	s.checked = now
+	p.maxWorkers = s.size()
+}
+
+// MaxWorkers 获取协程池当前的最大 worker 数量
+func (p *Pool) MaxWorkers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.maxWorkers
+}
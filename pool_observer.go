@@ -0,0 +1,99 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PoolObserver 协程池的观测回调，可用于接入外部监控。回调在协程池的 worker 协程中同步执行，应尽量轻量
+type PoolObserver interface {
+	// OnEnqueue 任务因没有空闲 worker 而进入等待队列，queueLen 为入队后的队列长度
+	OnEnqueue(class RunClass, queueLen int)
+	// OnTaskStart 任务开始执行，wait 为任务在队列中等待的时间
+	OnTaskStart(wait time.Duration)
+	// OnTaskFinish 任务执行结束，cost 为任务执行耗时
+	OnTaskFinish(cost time.Duration)
+	// OnWorkerSpawn 新建了 worker 协程，workers 为新建后的 worker 数
+	OnWorkerSpawn(workers int)
+	// OnWorkerRetire worker 协程因没有任务而退出，workers 为退出后的 worker 数
+	OnWorkerRetire(workers int)
+}
+
+// PoolStats 内置的 PoolObserver，以原子计数器汇总协程池的运行情况
+type PoolStats struct {
+	enqueued  atomic.Int64
+	started   atomic.Int64
+	finished  atomic.Int64
+	spawned   atomic.Int64
+	retired   atomic.Int64
+	totalWait atomic.Int64
+	totalCost atomic.Int64
+}
+
+// PoolStatsSnapshot PoolStats 某一时刻的快照
+type PoolStatsSnapshot struct {
+	Enqueued  int64         // 进入等待队列的任务数
+	Started   int64         // 开始执行的任务数
+	Finished  int64         // 执行结束的任务数
+	Spawned   int64         // 新建的 worker 数
+	Retired   int64         // 退出的 worker 数
+	TotalWait time.Duration // 任务在队列中等待的总时间
+	TotalCost time.Duration // 任务执行的总耗时
+}
+
+func (s *PoolStats) OnEnqueue(RunClass, int) {
+	s.enqueued.Add(1)
+}
+
+func (s *PoolStats) OnTaskStart(wait time.Duration) {
+	s.started.Add(1)
+	s.totalWait.Add(int64(wait))
+}
+
+func (s *PoolStats) OnTaskFinish(cost time.Duration) {
+	s.finished.Add(1)
+	s.totalCost.Add(int64(cost))
+}
+
+func (s *PoolStats) OnWorkerSpawn(int) {
+	s.spawned.Add(1)
+}
+
+func (s *PoolStats) OnWorkerRetire(int) {
+	s.retired.Add(1)
+}
+
+// observeTask 以 PoolObserver 的回调统计本次运行提交到协程池的任务，使运行级指标与协程池观测使用同一套接口。
+// 等待时间从提交时开始计算，入队与 worker 的新建、退出由协程池决定，不属于单次运行，不统计
+func (ctx *dagCtx) observeTask(f func()) func() {
+	stats := ctx.poolStats
+	if stats == nil {
+		return f
+	}
+	submitted := time.Now()
+	return func() {
+		begin := time.Now()
+		stats.OnTaskStart(begin.Sub(submitted))
+		defer func() {
+			stats.OnTaskFinish(time.Since(begin))
+		}()
+		f()
+	}
+}
+
+func (s *PoolStats) Snapshot() PoolStatsSnapshot {
+	return PoolStatsSnapshot{
+		Enqueued:  s.enqueued.Load(),
+		Started:   s.started.Load(),
+		Finished:  s.finished.Load(),
+		Spawned:   s.spawned.Load(),
+		Retired:   s.retired.Load(),
+		TotalWait: time.Duration(s.totalWait.Load()),
+		TotalCost: time.Duration(s.totalCost.Load()),
+	}
+}
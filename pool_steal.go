@@ -0,0 +1,94 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"slices"
+	"time"
+)
+
+// IPinnedPool 支持提交不可被窃取任务的协程池
+type IPinnedPool interface {
+	IPool
+	SubmitPinned(f func(), class RunClass)
+}
+
+// StealGroup 协程池的工作窃取组，组内协程池的 worker 在本池队列为空时，先执行同组其他协程池排队中的任务再退出；
+// 任务因协程池饱和而排队时，唤醒组内有空闲名额的协程池窃取，提高负载不均衡时的利用率。
+// 被窃取的任务计入其所属协程池的观测回调（OnTaskStart、OnTaskFinish）。协程池最多属于一个窃取组
+type StealGroup struct {
+	pools []*Pool
+}
+
+// NewStealGroup 创建工作窃取组，需在提交任务之前创建
+func NewStealGroup(pools ...*Pool) *StealGroup {
+	g := &StealGroup{pools: slices.Clone(pools)}
+	for _, p := range g.pools {
+		p.stealGroup = g
+	}
+	return g
+}
+
+// steal 从 thief 以外的协程池中窃取一个可被窃取的任务，返回任务所属的协程池，没有可窃取的任务时为 nil。调用时不能持有任何协程池的锁
+func (g *StealGroup) steal(thief *Pool) (func(), time.Time, *Pool) {
+	for _, victim := range g.pools {
+		if victim == thief {
+			continue
+		}
+		victim.mu.Lock()
+		for i := range victim.queues {
+			if f, enqueued, ok := victim.queues[i].popStealable(); ok {
+				victim.mu.Unlock()
+				return f, enqueued, victim
+			}
+		}
+		victim.mu.Unlock()
+	}
+	return nil, time.Time{}, nil
+}
+
+// wake 为 victim 以外第一个有空闲名额的协程池新建 worker，新建的 worker 直接窃取任务，没有可窃取的任务时退出。调用时不能持有任何协程池的锁
+func (g *StealGroup) wake(victim *Pool) {
+	for _, thief := range g.pools {
+		if thief == victim {
+			continue
+		}
+		thief.mu.Lock()
+		if thief.workers >= thief.maxWorkers {
+			thief.mu.Unlock()
+			continue
+		}
+		thief.workers++
+		workers := thief.workers
+		thief.mu.Unlock()
+		for _, o := range thief.observers {
+			o.OnWorkerSpawn(workers)
+		}
+		go thief.work(nil)
+		return
+	}
+}
+
+// SubmitPinned 按运行类别提交不可被窃取的任务
+func (p *Pool) SubmitPinned(f func(), class RunClass) {
+	p.submit(f, class, true)
+}
+
+// popStealable 取出队列中第一个可被窃取的任务
+func (q *taskQueue) popStealable() (func(), time.Time, bool) {
+	for prev, t := q.head, q.head.next; t != nil; prev, t = t, t.next {
+		if t.pinned {
+			continue
+		}
+		prev.next = t.next
+		if q.tail == t {
+			q.tail = prev
+		}
+		q.len--
+		return t.f, t.enqueued, true
+	}
+	return nil, time.Time{}, false
+}
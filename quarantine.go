@@ -0,0 +1,75 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"sync"
+	"time"
+)
+
+// quarantine 节点的隔离状态，在同一个图的所有运行间共享
+type quarantine struct {
+	after    uint
+	cooldown time.Duration
+	mu       sync.Mutex
+	failures uint      // 连续失败的运行次数
+	until    time.Time // 隔离截止时间，零值且 failures 达到阈值时表示一直隔离到手动重置
+}
+
+func newQuarantine(after uint, cooldown time.Duration) *quarantine {
+	if after == 0 {
+		return nil
+	}
+	return &quarantine{after: after, cooldown: cooldown}
+}
+
+// quarantined 节点当前是否处于隔离中
+func (q *quarantine) quarantined() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.failures < q.after {
+		return false
+	}
+	return q.until.IsZero() || time.Now().Before(q.until)
+}
+
+// record 记录一次实际执行的结果，连续失败达到阈值时开始隔离。冷却结束后的首次执行若再次失败，会立即重新隔离
+func (q *quarantine) record(succeeded bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if succeeded {
+		q.failures = 0
+		q.until = time.Time{}
+		return
+	}
+	q.failures++
+	if q.failures >= q.after && q.cooldown > 0 {
+		q.until = time.Now().Add(q.cooldown)
+	}
+}
+
+func (q *quarantine) reset() {
+	q.record(true)
+}
+
+// ResetQuarantine 解除节点的隔离状态并清零连续失败次数，未传入名称时重置所有节点
+func (dag *DAG[T]) ResetQuarantine(names ...string) {
+	for _, node := range dag.metaNodes {
+		if node.quarantine == nil {
+			continue
+		}
+		if len(names) == 0 {
+			node.quarantine.reset()
+			continue
+		}
+		for _, name := range names {
+			if node.name == name {
+				node.quarantine.reset()
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+// ErrSanitizer 在错误写入 NodeResult 之前对其脱敏，避免错误中携带的密钥等敏感信息出现在导出、日志与回调中
+type ErrSanitizer func(err error) error
+
+// RedactedErr 脱敏后的错误，Error 返回脱敏后的信息，仍可通过 errors.Is、errors.As 匹配原始错误
+type RedactedErr struct {
+	Msg string
+	err error
+}
+
+// Redact 以 replace 处理 err 的错误信息，返回脱敏后的错误，err 为 nil 时返回 nil
+func Redact(err error, replace func(msg string) string) error {
+	if err == nil {
+		return nil
+	}
+	return &RedactedErr{Msg: replace(err.Error()), err: err}
+}
+
+func (e *RedactedErr) Error() string {
+	return e.Msg
+}
+
+func (e *RedactedErr) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,88 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"time"
+)
+
+// RerunFailed 基于上一次的运行结果重新运行图：上次成功且所有祖先节点都无需重跑的节点直接复用上次的结果，
+// 其余节点（失败、未运行的节点及其所有后代）重新执行。prev 与当前图不匹配时重新运行整个图
+func (dag *DAG[T]) RerunFailed(prev *RunResult, params T) *RunResult {
+	return dag.RerunFailedWithOptions(prev, params, RunOptions{})
+}
+
+// Rerun 等同于 RerunFailed：上次成功的节点视为已完成，仅重新执行失败、跳过、取消与未运行的节点及其后代
+func (dag *DAG[T]) Rerun(prev *RunResult, params T) *RunResult {
+	return dag.RerunFailedWithOptions(prev, params, RunOptions{})
+}
+
+// RerunFailedWithOptions 与 RerunFailed 相同，可指定运行配置
+func (dag *DAG[T]) RerunFailedWithOptions(prev *RunResult, params T, opts RunOptions) *RunResult {
+	run := dag.newRun(opts)
+	run.planReuse(prev, func(int, *NodeResult) bool {
+		return true
+	})
+	run.dispatch(params)
+	<-run.done()
+	return run.result()
+}
+
+// RunWarm 基于上一次的运行结果增量运行图：设置了 CacheKey 的节点，若以本次 params 计算的输入键与上次相同、
+// 上次的结果在 staleness 内产生且所有祖先节点都被复用，则直接复用上次的结果（包括输出），其余节点重新执行。
+// 输入键在运行开始前计算，CacheKey 应只依赖调用方传入的参数，而不是上游节点写入的数据
+func (dag *DAG[T]) RunWarm(prev *RunResult, params T, staleness time.Duration) *RunResult {
+	return dag.RunWarmWithOptions(prev, params, staleness, RunOptions{})
+}
+
+// RunWarmWithOptions 与 RunWarm 相同，可指定运行配置
+func (dag *DAG[T]) RunWarmWithOptions(prev *RunResult, params T, staleness time.Duration, opts RunOptions) *RunResult {
+	run := dag.newRun(opts)
+	now := time.Now()
+	run.planReuse(prev, func(idx int, r *NodeResult) bool {
+		node := dag.metaNodes[idx]
+		if node.cacheKey == nil || r.InputKey != node.cacheKey(params) {
+			return false
+		}
+		// 未执行 processor 的节点没有开始时间，以上次运行的结束时间为准
+		finished := prev.End
+		if !r.Begin.IsZero() {
+			finished = r.Begin.Add(r.Cost)
+		}
+		return now.Sub(finished) <= staleness
+	})
+	run.dispatch(params)
+	<-run.done()
+	return run.result()
+}
+
+// planReuse 按拓扑序决定复用 prev 中的哪些结果：上次成功、keep 返回 true 且所有祖先节点都被复用的节点被复用。
+// prev 与当前图不匹配时不复用任何结果
+func (run *dagRun[T]) planReuse(prev *RunResult, keep func(idx int, r *NodeResult) bool) {
+	dag := run.dag
+	if prev == nil || len(prev.Results) != len(dag.metaNodes) {
+		return
+	}
+	rerun := make([]bool, len(dag.metaNodes))
+	run.reused = make([]*NodeResult, len(dag.metaNodes))
+	for _, idx := range dag.topoOrder() {
+		r := prev.Results[idx]
+		if r.Status != Succeeded || r.Name != dag.metaNodes[idx].name || !keep(idx, r) {
+			rerun[idx] = true
+		}
+		if !rerun[idx] {
+			run.reused[idx] = r
+			continue
+		}
+		node := dag.metaNodes[idx]
+		for _, childIdx := range node.children {
+			rerun[childIdx] = true
+		}
+		for _, weakChildIdx := range node.weakChildren {
+			rerun[weakChildIdx] = true
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"context"
+)
+
+// ResourceLimiter 按外部资源键（如 "db:orders"、"api:billing"）限制同时持有该资源的节点数，
+// 可被多个图的多次运行共享，使对同一外部依赖的并发访问在整个进程内受控
+type ResourceLimiter struct {
+	slots map[string]chan struct{}
+}
+
+// NewResourceLimiter 创建资源限制器，limits 为各资源键允许的最大并发持有数，小于1时被视为1，未配置的资源键不受限制
+func NewResourceLimiter(limits map[string]int) *ResourceLimiter {
+	l := &ResourceLimiter{slots: make(map[string]chan struct{}, len(limits))}
+	for key, limit := range limits {
+		if limit < 1 {
+			limit = 1
+		}
+		l.slots[key] = make(chan struct{}, limit)
+	}
+	return l
+}
+
+// acquire 按顺序获取各资源的名额，keys 需已排序去重以避免死锁，ctx 结束时归还已获取的名额并返回 false
+func (l *ResourceLimiter) acquire(ctx context.Context, keys []string) bool {
+	for i, key := range keys {
+		slots, ok := l.slots[key]
+		if !ok {
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			l.release(keys[:i])
+			return false
+		}
+	}
+	return true
+}
+
+// release 归还各资源的名额
+func (l *ResourceLimiter) release(keys []string) {
+	for _, key := range keys {
+		if slots, ok := l.slots[key]; ok {
+			<-slots
+		}
+	}
+}
+
+// Holding 当前持有该资源的节点数
+func (l *ResourceLimiter) Holding(key string) int {
+	return len(l.slots[key])
+}
@@ -0,0 +1,19 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+// ResultSink 在每个节点结束时立即接收其运行结果，可用于长时间运行的图增量持久化进度。
+// 节点可能并发结束，实现需保证并发安全
+type ResultSink interface {
+	OnResult(runID string, result *NodeResult)
+}
+
+// ResultSinkFunc 函数形式的 ResultSink
+type ResultSinkFunc func(runID string, result *NodeResult)
+
+func (f ResultSinkFunc) OnResult(runID string, result *NodeResult) {
+	f(runID, result)
+}
@@ -0,0 +1,67 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RetryBreaker 重试熔断器，可在多次运行间共享。进行中的重试数超过阈值时，说明下游可能出现了系统性故障，
+// 熔断器会在冷却时间内暂停新的重试，避免宽图中大量节点同时重试压垮共享的后端服务
+type RetryBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	mu        sync.Mutex
+	inFlight  int
+	openUntil time.Time
+}
+
+// NewRetryBreaker 创建重试熔断器，进行中的重试数超过 threshold 时熔断 cooldown 时长
+func NewRetryBreaker(threshold int, cooldown time.Duration) *RetryBreaker {
+	return &RetryBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// InFlight 当前进行中的重试数
+func (b *RetryBreaker) InFlight() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.inFlight
+}
+
+// acquire 熔断期间等待冷却结束，ctx 结束时返回 false
+func (b *RetryBreaker) acquire(ctx context.Context) bool {
+	for {
+		b.mu.Lock()
+		wait := time.Until(b.openUntil)
+		if wait <= 0 {
+			b.inFlight++
+			if b.inFlight > b.threshold {
+				b.openUntil = time.Now().Add(b.cooldown)
+			}
+			b.mu.Unlock()
+			return true
+		}
+		b.mu.Unlock()
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+func (b *RetryBreaker) release() {
+	b.mu.Lock()
+	b.inFlight--
+	b.mu.Unlock()
+}
@@ -0,0 +1,40 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+// ReverseOrder 返回逆拓扑序的节点名称，子节点总在父节点之前，可用于按依赖关系逆序清理资源
+func (dag *DAG[T]) ReverseOrder() []string {
+	order := dag.topoOrder()
+	names := make([]string, len(order))
+	for i, idx := range order {
+		names[len(order)-1-i] = dag.metaNodes[idx].name
+	}
+	return names
+}
+
+// Reverse 构建边方向相反的图（如由 provision 图生成 deprovision 图），强依赖与弱依赖分别映射为反向的强依赖与弱依赖。
+// build 根据原节点的定义生成新节点（通常只需设置 Processor 等执行配置），返回 nil 时生成仅带名称的空节点
+func (dag *DAG[T]) Reverse(build func(def NodeDefinition) *Node[T]) (*DAG[T], error) {
+	def := dag.Definition()
+	nodes := make([]*Node[T], len(dag.metaNodes))
+	for i, nodeDef := range def.Nodes {
+		if build != nil {
+			nodes[i] = build(nodeDef)
+		}
+		if nodes[i] == nil {
+			nodes[i] = &Node[T]{Name: nodeDef.Name}
+		}
+	}
+	for i, node := range dag.metaNodes {
+		for _, childIdx := range node.children {
+			nodes[i].AddDependency(nodes[childIdx])
+		}
+		for _, weakChildIdx := range node.weakChildren {
+			nodes[i].AddWeakDependency(nodes[weakChildIdx])
+		}
+	}
+	return NewDAG(nodes...)
+}
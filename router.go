@@ -0,0 +1,53 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+// RouteFunc 路由函数，返回需要激活的下游强依赖节点名称
+type RouteFunc[T any] func(node IRuntimeNode, params T) ([]string, error)
+
+// router 运行时节点记录路由结果的能力
+type router interface {
+	selectRoutes(names []string)
+}
+
+// NewRouterNode 创建路由节点，其 processor 返回需要激活的下游分支名称，
+// 未被选中的强依赖下游节点被标记为 Skipped（不受其 SkipPolicy 影响），更下游的节点按各自的 SkipPolicy 处理。
+// 中间件替换了传给 processor 的 IRuntimeNode 时，路由节点以 UnroutableErr 失败
+func NewRouterNode[T any](name string, route RouteFunc[T]) *Node[T] {
+	return &Node[T]{
+		Name: name,
+		Processor: func(node IRuntimeNode, params T) error {
+			names, err := route(node, params)
+			if err != nil {
+				return err
+			}
+			r, ok := node.(router)
+			if !ok {
+				return UnroutableErr
+			}
+			node.DoIfRunning(func() {
+				r.selectRoutes(names)
+			})
+			return nil
+		},
+	}
+}
+
+func (node *runtimeNode[T]) selectRoutes(names []string) {
+	node.routes = make(map[string]struct{}, len(names))
+	for _, name := range names {
+		node.routes[name] = struct{}{}
+	}
+}
+
+// routesOut 子节点是否未被路由选中
+func (node *runtimeNode[T]) routesOut(child *runtimeNode[T]) bool {
+	if node.routes == nil {
+		return false
+	}
+	_, ok := node.routes[child.name]
+	return !ok
+}
@@ -0,0 +1,129 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"context"
+	"errors"
+)
+
+// RunHandle 异步运行的句柄，可等待、取消运行或获取运行结果
+type RunHandle struct {
+	done   <-chan struct{}
+	abort  func(cause error)
+	pause  func()
+	resume func()
+	kill   func(name string) bool
+	result func() *RunResult
+}
+
+// RunAsync 异步运行图，立即返回运行句柄
+func (dag *DAG[T]) RunAsync(params T) *RunHandle {
+	return dag.RunAsyncWithOptions(params, RunOptions{})
+}
+
+// RunAsyncWithOptions 按指定配置异步运行图，立即返回运行句柄，根节点在独立的协程内调度
+func (dag *DAG[T]) RunAsyncWithOptions(params T, opts RunOptions) *RunHandle {
+	run := dag.newRun(opts)
+	run.prepare(params)
+	go run.startRoots(params)
+	return &RunHandle{
+		done:   run.done(),
+		abort:  run.ctx.abort,
+		pause:  run.ctx.pause,
+		resume: run.ctx.resume,
+		kill:   run.kill,
+		result: run.result,
+	}
+}
+
+// Done 返回运行结束时关闭的 channel，可用于 select
+func (h *RunHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait 等待运行结束并返回运行结果，可重复调用
+func (h *RunHandle) Wait() *RunResult {
+	<-h.done
+	return h.result()
+}
+
+// Cancel 取消运行，错误为 context.Canceled，与 RunOptions.Context 被取消的效果相同，运行结束后调用无影响
+func (h *RunHandle) Cancel() {
+	select {
+	case <-h.done:
+	default:
+		h.abort(context.Canceled)
+	}
+}
+
+// Pause 暂停运行，之后就绪的节点不再被调度，正在执行的 processor 不受影响，运行被取消时自动恢复
+func (h *RunHandle) Pause() {
+	h.pause()
+}
+
+// Resume 恢复运行，调度暂停期间就绪的节点
+func (h *RunHandle) Resume() {
+	h.resume()
+}
+
+// Kill 立即以 OperatorKilledErr 终止名为 name 的运行中（包括已就绪、排队中）的节点，返回是否有节点被终止，存在重名节点时全部终止。
+// 下游节点按强弱依赖、SkipPolicy 与 FailFast 等配置处理，其余节点不受影响。执行中节点的上下文（IRuntimeNode.Context）以 OperatorKilledErr 被取消；
+// 设置了超时的节点下游立即被通知，未设置超时的节点下游在 processor 返回后才被通知。processor 之后的重试不再进行
+func (h *RunHandle) Kill(name string) bool {
+	return h.kill(name)
+}
+
+// Results 运行已结束时返回运行结果，否则返回 nil
+func (h *RunHandle) Results() *RunResult {
+	select {
+	case <-h.done:
+		return h.Wait()
+	default:
+		return nil
+	}
+}
+
+// AllOf 等待所有运行结束，返回各运行的结果（顺序与 handles 一致），以及由各失败运行的 RunResult.Err 按顺序合并（errors.Join）的错误。
+// 某次运行失败不会影响其余运行
+func AllOf(handles ...*RunHandle) ([]*RunResult, error) {
+	results := make([]*RunResult, len(handles))
+	errs := make([]error, len(handles))
+	for i, h := range handles {
+		results[i] = h.Wait()
+		errs[i] = results[i].Err()
+	}
+	return results, errors.Join(errs...)
+}
+
+// AnyOf 返回最先成功（RunResult.Err 为 nil）的运行的下标与结果，并取消其余运行，不等待其结束。
+// 所有运行都失败时返回 -1、nil 与合并后的错误，handles 为空时返回 -1、nil、nil
+func AnyOf(handles ...*RunHandle) (int, *RunResult, error) {
+	if len(handles) == 0 {
+		return -1, nil, nil
+	}
+	finished := make(chan int, len(handles))
+	for i, h := range handles {
+		go func() {
+			<-h.Done()
+			finished <- i
+		}()
+	}
+	errs := make([]error, len(handles))
+	for range handles {
+		i := <-finished
+		result := handles[i].Wait()
+		if errs[i] = result.Err(); errs[i] == nil {
+			for j, h := range handles {
+				if j != i {
+					h.Cancel()
+				}
+			}
+			return i, result, nil
+		}
+	}
+	return -1, nil, errors.Join(errs...)
+}
@@ -0,0 +1,23 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+// RunHooks 运行级的生命周期钩子函数，用于日志、追踪等横切关注点，无需在每个节点上单独设置。各字段为 nil 时表示不回调
+type RunHooks struct {
+	// OnRunStart 运行开始调度根节点之前调用
+	OnRunStart func(runID string)
+	// OnRunFinish 运行结束时调用一次，在 Run 返回、RunHandle.Done 关闭之前于最后结束的节点所在协程内调用
+	OnRunFinish func(result *RunResult)
+	// OnNodeStateChange 节点状态变化时调用，包括 Waiting→Running 与 Running→终态，可能被并发调用
+	OnNodeStateChange func(runID string, node string, from, to NodeStatus)
+}
+
+// stateChanged 回调节点状态变化
+func (ctx *dagCtx) stateChanged(node string, from, to NodeStatus) {
+	if ctx.hooks != nil && ctx.hooks.OnNodeStateChange != nil {
+		ctx.hooks.OnNodeStateChange(ctx.runID, node, from, to)
+	}
+}
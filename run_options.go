@@ -0,0 +1,114 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"context"
+	"time"
+)
+
+// RunClass 运行类别，支持 IClassPool 的协程池会优先执行交互类运行的节点
+type RunClass int
+
+const (
+	// ClassInteractive 交互类运行，如处理请求链路上的图，默认类别
+	ClassInteractive RunClass = iota
+	// ClassBatch 批处理运行，如后台任务，仅使用协程池的空闲能力
+	ClassBatch
+	runClassCnt
+)
+
+// DispatchOrder 同时就绪的节点（包括根节点）的调度顺序，在协程池远小于就绪节点数时影响执行顺序
+type DispatchOrder int
+
+const (
+	// DispatchDefault 按图内节点顺序调度
+	DispatchDefault DispatchOrder = iota
+	// DispatchByPriority 按 Node.Priority 从大到小调度
+	DispatchByPriority
+	// DispatchByWeight 按节点到叶子节点的最大预估权重和（Node.Weight）从大到小调度，优先推进关键路径
+	DispatchByWeight
+	dispatchOrderCnt
+)
+
+// RunOptions 图单次运行的配置
+type RunOptions struct {
+	// Context 运行的上下文，取消时尚未开始执行的节点将被标记为 Cancelled，错误为 context.Cause，为 nil 时视为 context.Background()
+	Context context.Context
+	// Timeout 运行的整体超时时间，超时后尚未开始执行的节点将被标记为 Cancelled，错误为 TimeoutErr，小于或等于0时表示不限制
+	Timeout time.Duration
+	// Pool 协程池，为 nil 时每个节点使用独立的协程运行
+	Pool IPool
+	// Class 运行类别，仅在 Pool 实现了 IClassPool 时生效
+	Class RunClass
+	// DispatchOrder 同时就绪的节点的调度顺序
+	DispatchOrder DispatchOrder
+	// MaxConcurrency 本次运行最多同时执行的节点数，小于或等于0时表示不限制，可与 Limiter 同时使用
+	MaxConcurrency int
+	// Disabled 本次运行禁用的节点名称，被禁用的节点不执行，以 Cancelled 结束，错误为 DisabledErr，其强依赖的下游节点同样不会执行
+	Disabled []string
+	// Targets 本次运行的目标节点名称，仅执行目标节点及其所有祖先（强依赖与弱依赖），其余节点以 Skipped 结束且不会提交到协程池，为空时执行整个图。
+	// 不存在的名称被忽略，指定的名称均不存在时所有节点以 Skipped 结束
+	Targets []string
+	// IncludeTags 设置了 Tags 的节点仅在至少有一个标签属于 IncludeTags 时执行，未设置 Tags 的节点不受影响，为空时不过滤。
+	// ExcludeTags 有任一标签属于 ExcludeTags 的节点不执行。被过滤的节点以 Skipped 结束，其强依赖的下游节点按各自的 SkipPolicy 处理
+	IncludeTags []string
+	ExcludeTags []string
+	// Limiter 并发限制器，多个图共享同一个限制器时可限制整个进程内同时执行的节点总数
+	Limiter *Limiter
+	// Resources 外部资源限制器，与 Node.Resources 配合，限制多个图、多次运行对同一外部资源的并发访问
+	Resources *ResourceLimiter
+	// Admission 准入控制，为 nil 时所有就绪节点直接执行
+	Admission AdmissionController
+	// Leases Exclusive 节点跨进程互斥所用的租约存储，LeaseTTL 为租约时长，小于或等于0时为30秒，
+	// LeaseHolder 为租约持有者标识的前缀（如主机名与进程号），实际的持有者标识为 LeaseHolder/运行ID/序号，每次获取租约时唯一
+	Leases      LeaseStore
+	LeaseTTL    time.Duration
+	LeaseHolder string
+	// FailFast 任一节点失败时立即终止运行，未开始执行的节点被标记为 Cancelled，错误为 *FailFastErr，
+	// 正在执行的 processor 可通过 IRuntimeNode.Context 感知，重试与退避等待会被中断
+	FailFast bool
+	// RunID 运行 ID，为空时自动生成
+	RunID string
+	// Parent 在节点的 processor 内运行子图时传入该节点，子图的运行 ID 将嵌套在父运行 ID 之下，运行结果会挂载到父节点的 NodeResult.SubRuns 中。
+	// 子图未设置的 Mock、EventSink、ErrSanitizer 继承自父运行，父运行设置了 FailFast 时子图同样快速失败
+	Parent IRuntimeNode
+	// RetryBreaker 重试熔断器，进行中的重试过多时暂停新的重试，多次运行共享同一个熔断器时可跨运行生效
+	RetryBreaker *RetryBreaker
+	// Container 依赖注入容器，processor 可通过 IRuntimeNode.Container 或 Resolve 获取其中的对象
+	Container *Container
+	// ResultSink 节点结束时立即接收其运行结果，包括复用历史结果的节点
+	ResultSink ResultSink
+	// PanicScope processor 发生 panic 时影响的范围，默认与其他错误相同
+	PanicScope PanicScope
+	// PanicHandler processor 等发生 panic 时的回调，接收节点、recover 得到的值与堆栈，为 nil 时不回调。
+	// 无论是否设置，节点的 *PanicErr 都带有堆栈
+	PanicHandler PanicHandler
+	// Hooks 运行级的生命周期钩子函数，为 nil 时使用 BuildOptions.Hooks
+	Hooks *RunHooks
+	// Checkpoint 在每个节点结束时保存检查点（状态与序列化后的输出），进程崩溃后可通过 DAG.Resume 以相同的运行 ID 恢复运行。
+	// 保存失败不影响运行，第一个错误记录在 RunResult.CheckpointErr 中
+	Checkpoint CheckpointStore
+	// Codec 检查点中节点输出的序列化方式，为 nil 时使用 JSONCodec
+	Codec Codec
+	// Lazy 懒加载运行时节点，节点在依赖就绪时才创建，适合节点数很多而单次运行只会推进一小部分的图。
+	// 未创建的节点在 RunResult 中以 Waiting（运行被取消时为 Cancelled）状态出现
+	Lazy bool
+	// ErrSanitizer 错误的脱敏函数，作用于 RunResult、钩子函数与 ResultSink 收到的 NodeResult.Err，以及 RunResult.Cause 与 EventSink 事件中的错误，为 nil 时不脱敏
+	ErrSanitizer ErrSanitizer
+	// EventSink 接收运行开始、节点开始与结束、运行结束等结构化事件（见 RunEvent）
+	EventSink EventSink
+	// SampleRate 详细观测数据（并发度采样、EventSink 事件）的采样率，取值在 (0, 1) 之间时按运行 ID 确定性地采样，
+	// 未被采样的运行仍记录峰值等汇总指标，小于或等于0或大于等于1时总是采集
+	SampleRate float64
+	// CanaryRate 执行金丝雀实现（见 Node.Canary）的运行比例，按运行 ID 确定性地选择，同一次运行中所有设置了 Canary 的节点都执行金丝雀实现。
+	// 小于或等于0时从不执行，大于等于1时总是执行
+	CanaryRate float64
+	// Mock 模拟运行的配置，不为 nil 时所有 processor 被替换为固定耗时且总是成功的模拟函数
+	Mock *MockOptions
+	// Labels 运行标签，原样记录在 RunResult 中，便于区分不同的运行
+	Labels map[string]string
+}
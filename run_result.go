@@ -0,0 +1,125 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"iter"
+	"time"
+)
+
+// RunResult 图单次运行的完整记录
+type RunResult struct {
+	ID       string // 运行 ID
+	ParentID string // 作为子图运行时，父图的运行 ID
+	// Fingerprint 运行的图的指纹，见 DAG.Fingerprint
+	Fingerprint uint64
+	Begin       time.Time         // 图开始运行的时间
+	End         time.Time         // 图运行结束的时间
+	Cost        time.Duration     // 图运行的总耗时
+	Pool        IPool             // 本次运行使用的协程池，为 nil 时表示未使用协程池
+	Labels      map[string]string // 本次运行的标签
+	Cause       error             // 运行被终止的原因，未被终止时为 nil
+	// Degraded 在有弱依赖失败的情况下运行的节点数，可作为降级率指标
+	Degraded int
+	// SLABreaches 本次运行的 SLA 违约，见 BuildOptions.SLA 与 Node.SLA，每个违约同时作为 sla.breached 事件发送
+	SLABreaches []SLABreach
+	// Sampled 本次运行是否被采样，未被采样时 Concurrency 为空，也不会发送 EventSink 事件
+	Sampled bool
+	// Canary 本次运行是否被选中执行金丝雀实现，见 RunOptions.CanaryRate
+	Canary bool
+	// PeakConcurrency 运行期间同时执行 processor 的最大节点数
+	PeakConcurrency int
+	// Concurrency 并发度变化的采样，可用于判断协程池大小或依赖结构是否为瓶颈
+	Concurrency []ConcurrencySample
+	// PeakRetrying 运行期间同时进行中的最大重试数
+	PeakRetrying int
+	// PoolStats 本次运行提交到 RunOptions.Pool 的任务的开始、结束数与等待、执行耗时，由 PoolStats 按 PoolObserver 的回调汇总。
+	// 运行在最后一个任务内结束，该任务不计入 Finished 与 TotalCost。未设置协程池或未被采样时为零值
+	PoolStats PoolStatsSnapshot
+	// CheckpointErr 保存检查点时的第一个错误，见 RunOptions.Checkpoint
+	CheckpointErr error
+	// DataBus 本次运行的数据总线，运行结束后可通过 Lookup 读取节点写入的数据
+	DataBus *DataBus
+	Results []*NodeResult // 各节点的运行结果，顺序与图内节点顺序一致
+	index   any           // 用户节点 -> 结果下标，类型为 map[*Node[T]]int
+}
+
+// Err 返回运行的汇总错误，运行被终止或存在失败节点时返回 *RunErr（各失败节点的错误以 *NodeError 包装），否则返回 nil
+func (result *RunResult) Err() error {
+	err := &RunErr{Cause: result.Cause}
+	for r := range result.Failed() {
+		err.Nodes = append(err.Nodes, r.Name)
+		err.Errs = append(err.Errs, &NodeError{NodeName: r.Name, Attempt: r.Attempts, Cause: r.Err})
+	}
+	if err.Cause == nil && len(err.Nodes) == 0 {
+		return nil
+	}
+	return err
+}
+
+// ByName 按节点名称获取运行结果，不存在时返回 nil，存在重名节点时返回图内顺序的第一个。
+// 本图内没有该名称的节点时，按 Flatten 的带前缀名称（如 "sub/inner"）查找子图节点的结果副本
+func (result *RunResult) ByName(name string) *NodeResult {
+	for _, r := range result.Results {
+		if r.Name == name {
+			return r
+		}
+	}
+	for r := range result.Flatten() {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// ByNode 获取节点在本次运行中的结果，节点不属于该图时返回 nil
+func ByNode[T any](result *RunResult, node *Node[T]) *NodeResult {
+	index, ok := result.index.(map[*Node[T]]int)
+	if !ok {
+		return nil
+	}
+	idx, ok := index[node]
+	if !ok || idx >= len(result.Results) {
+		return nil
+	}
+	return result.Results[idx]
+}
+
+// All 按图内节点顺序遍历各节点的运行结果
+func (result *RunResult) All() iter.Seq[*NodeResult] {
+	return func(yield func(*NodeResult) bool) {
+		for _, r := range result.Results {
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+// Partial 运行是否部分失败，即运行未被终止、存在失败的节点且有其他节点执行成功
+func (result *RunResult) Partial() bool {
+	if result.Cause != nil {
+		return false
+	}
+	var failed, succeeded bool
+	for r := range result.All() {
+		failed = failed || r.Status == Failed
+		succeeded = succeeded || r.Status == Succeeded
+	}
+	return failed && succeeded
+}
+
+// Failed 按图内节点顺序遍历失败节点的运行结果
+func (result *RunResult) Failed() iter.Seq[*NodeResult] {
+	return func(yield func(*NodeResult) bool) {
+		for r := range result.All() {
+			if r.Status == Failed && !yield(r) {
+				return
+			}
+		}
+	}
+}
@@ -6,8 +6,11 @@
 package easydag
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"math"
+	"slices"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -23,37 +26,106 @@ type IRuntimeNode interface {
 	DoIfRunning(fn func()) bool
 	// GetDDL 获取节点的最终截止时间（ddl）、是否获取成功
 	GetDDL() (time.Time, bool)
+	// RemainingBudget 获取距节点截止时间与运行截止时间中较早者的剩余时间，可按比例作为下游调用的超时时间。
+	// 已超过截止时间时返回0，没有截止时间时返回 math.MaxInt64
+	RemainingBudget() time.Duration
 	// GetCost 获取节点执行耗时，包括多次重试的总时间、重试的退避时间、超时后继续执行的时间
 	GetCost() time.Duration
 	// GetAttempts 获取节点运行次数
 	GetAttempts() uint
+	// Config 获取节点的静态配置，返回值在多次运行间共享，不可修改
+	Config() map[string]string
+	// Meta 获取节点的元数据，返回值在多次运行间共享，不可修改
+	Meta() map[string]any
+	// Tags 获取节点排序去重后的标签，返回值在多次运行间共享，不可修改
+	Tags() []string
+	// ExecutionID 获取本次执行的唯一标识，格式为 运行ID/节点名称/运行次数，可附加到对外请求中，以便在下游系统中追踪某一次重试
+	ExecutionID() string
+	// Container 获取本次运行的依赖注入容器，未设置时返回 nil，nil 容器的 Get 方法始终返回 false
+	Container() *Container
+	// GetStatus 获取节点当前的运行状态
+	GetStatus() NodeStatus
+	// AwaitDep 等待名为 name 的直接依赖（强依赖或弱依赖）结束，最多等待 timeout，返回依赖的状态与是否已结束。
+	// name 不是当前节点的依赖、等待超时或 Context 结束时返回 false，通常用于设置了 Node.EarlyStart 的节点等待进行中的弱依赖
+	AwaitDep(name string, timeout time.Duration) (NodeStatus, bool)
+	// SetOutput 设置节点的输出，仅在未超时时生效，多次调用时以最后一次为准
+	SetOutput(output any)
+	// Input 读取名为 name 的直接依赖节点的输出，依赖不存在、未结束或没有输出时返回 false，通常通过 InputOf 调用
+	Input(name string) (any, bool)
+	// DataBus 获取本次运行的数据总线，通常通过 Publish 与 Subscribe 访问
+	DataBus() *DataBus
+	// Context 获取节点执行的上下文，运行被取消或节点超时（截止时间同 GetDDL）时结束，超时时 context.Cause 为 TimeoutErr。
+	// processor 应在耗时操作中监听该上下文，避免超时后仍在后台运行
+	Context() context.Context
 }
 
 // runtimeNode dag每次运行时创建的节点，是有状态的
 type runtimeNode[T any] struct {
 	*nodeMetadata[T]
+	idx int // 节点在图内的下标
+	// localTimeout 本次运行生效的本地超时时间，覆盖元数据中的同名字段，继承运行截止时间时与元数据不同
+	localTimeout time.Duration
+	dagRun       *dagRun[T]
 	ctx          *dagCtx
+	execCtx      context.Context // 节点执行的上下文，可被 Kill 取消，设置了超时时带有截止时间
+	execCancel   context.CancelCauseFunc
+	awaited      bool // processor 是否在独立的任务中执行，由 processWithTimeout 等待其结束后通知子节点
 	doneDepCnt   atomic.Int32
-	children     []*runtimeNode[T]
-	weakChildren []*runtimeNode[T]
-	status       atomic.Int32
+	firstDepDone atomic.Int64      // 第一个依赖完成的时间（UnixNano），仅对有多个依赖的节点记录
+	strongDone   atomic.Int64      // 最慢的强依赖完成的时间（UnixNano），仅对设置了 InheritTimeout 的节点记录
+	doneStrong   atomic.Int32      // 已完成的强依赖数，仅对设置了 EarlyStart 的节点记录
+	children     []*runtimeNode[T] // 懒加载模式下为 nil，通过元数据中的下标查找
+	weakChildren []*runtimeNode[T] // 懒加载模式下为 nil，通过元数据中的下标查找
+	status       atomicStatus
 	done         chan struct{}
+	finished     chan struct{} // 节点进入终态时关闭
+	settled      chan struct{} // processor 结束后节点进入终态、钩子函数调用完毕时关闭
 	err          error
 	// mu 与超时控制互斥，故仅在超时时加写锁（排他锁），其余情况加读锁（共享锁）
-	mu       sync.RWMutex
-	begin    time.Time
-	ddl      time.Time
-	cost     atomic.Int64
-	attempts uint
+	mu         sync.RWMutex
+	ready      time.Time
+	begin      time.Time
+	ddl        time.Time
+	ddlBy      error // ddl 对应的截止时间，为 LocalTimeoutErr 或 TotalTimeoutErr
+	cost       atomic.Int64
+	attempts   uint
+	degraded   atomic.Bool // 是否有弱依赖失败
+	depSkipped atomic.Bool // 是否有强依赖被跳过
+	routedOut  atomic.Bool // 是否未被上游路由节点选中
+	// upstreamPanic 以 PanicScopeBranch 隔离时，上游发生 panic 的节点，不为 nil 时节点被跳过
+	upstreamPanic atomic.Pointer[BranchPanicErr]
+	// depCondPanic 强依赖的边条件发生 panic 时的错误，不为 nil 时节点以该错误失败
+	depCondPanic atomic.Pointer[PanicErr]
+	routes       map[string]struct{} // 路由节点选中的下游节点名称，为 nil 时表示不是路由节点
+	cacheHit     bool
+	inputKey     string // CacheKey 计算的输入键
+	output       any    // 数据流模式下节点的输出
+	persistErr   error
+	lease        *lease // 执行期间持有的租约，见 Node.Exclusive
+	// reused 复用的历史运行结果，不为 nil 时节点不会执行 processor 与钩子函数，直接视为成功
+	reused *NodeResult
+	// subRuns 在节点内运行的子图结果，可能被并发写入
+	subRunsMu sync.Mutex
+	subRuns   []*RunResult
 }
 
-func newRuntimeNode[T any](metaData *nodeMetadata[T], ctx *dagCtx) *runtimeNode[T] {
+// subRunParent 运行时节点作为子图父节点时需要的能力，不对外暴露以免影响 IRuntimeNode 的实现
+type subRunParent interface {
+	runID() string
+	runCtx() *dagCtx
+	attachSubRun(result *RunResult)
+}
+
+func newRuntimeNode[T any](idx int, metaData *nodeMetadata[T], run *dagRun[T]) *runtimeNode[T] {
 	return &runtimeNode[T]{
+		idx:          idx,
 		nodeMetadata: metaData,
-		ctx:          ctx,
-		children:     make([]*runtimeNode[T], 0, len(metaData.children)),
-		weakChildren: make([]*runtimeNode[T], 0, len(metaData.weakChildren)),
+		localTimeout: metaData.localTimeout,
+		dagRun:       run,
+		ctx:          run.ctx,
 		done:         make(chan struct{}),
+		finished:     make(chan struct{}),
+		settled:      make(chan struct{}),
 	}
 }
 
@@ -78,6 +150,17 @@ func (node *runtimeNode[T]) GetDDL() (time.Time, bool) {
 	return node.ddl, true
 }
 
+func (node *runtimeNode[T]) RemainingBudget() time.Duration {
+	ddl, ok := node.Context().Deadline()
+	if !ok {
+		return math.MaxInt64
+	}
+	if remaining := time.Until(ddl); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
 func (node *runtimeNode[T]) GetCost() time.Duration {
 	node.mu.RLock()
 	defer node.mu.RUnlock()
@@ -89,92 +172,670 @@ func (node *runtimeNode[T]) GetCost() time.Duration {
 	}
 }
 
+func (node *runtimeNode[T]) GetStatus() NodeStatus {
+	return node.status.Load()
+}
+
+func (node *runtimeNode[T]) AwaitDep(name string, timeout time.Duration) (NodeStatus, bool) {
+	for _, idx := range node.deps {
+		if node.dagRun.dag.metaNodes[idx].name != name {
+			continue
+		}
+		dep := node.dagRun.node(idx)
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-dep.finished:
+			return dep.status.Load(), true
+		case <-timer.C:
+		case <-node.Context().Done():
+		}
+		return dep.status.Load(), false
+	}
+	return Waiting, false
+}
+
 func (node *runtimeNode[T]) GetAttempts() uint {
 	return node.attempts
 }
 
+func (node *runtimeNode[T]) Config() map[string]string {
+	return node.config
+}
+
+func (node *runtimeNode[T]) Meta() map[string]any {
+	return node.meta
+}
+
+func (node *runtimeNode[T]) Tags() []string {
+	return node.tags
+}
+
+func (node *runtimeNode[T]) ExecutionID() string {
+	return node.ctx.runID + "/" + node.name + "/" + strconv.FormatUint(uint64(node.attempts), 10)
+}
+
+func (node *runtimeNode[T]) Container() *Container {
+	return node.ctx.container
+}
+
+func (node *runtimeNode[T]) DataBus() *DataBus {
+	return node.ctx.bus
+}
+
+func (node *runtimeNode[T]) Context() context.Context {
+	if node.execCtx == nil {
+		return node.ctx.ctx
+	}
+	return node.execCtx
+}
+
+func (node *runtimeNode[T]) runID() string {
+	return node.ctx.runID
+}
+
+func (node *runtimeNode[T]) runCtx() *dagCtx {
+	return node.ctx
+}
+
+func (node *runtimeNode[T]) attachSubRun(result *RunResult) {
+	node.subRunsMu.Lock()
+	node.subRuns = append(node.subRuns, result)
+	node.subRunsMu.Unlock()
+}
+
 func (node *runtimeNode[T]) start(params T) {
-	if !node.status.CompareAndSwap(Waiting, Running) {
+	if !node.markReady() {
 		return
 	}
-	node.ctx.wg.Add(1)
-	if node.ctx.pool == nil {
-		go node.run(params)
+	node.schedule(params)
+}
+
+// schedule 调度已标记为运行中的节点，内联节点与被裁剪的节点在当前协程内执行，但不在当前协程内续跑子节点
+func (node *runtimeNode[T]) schedule(params T) {
+	// 被裁剪的节点不执行 processor，直接结束
+	if (node.inline || node.dagRun.isPruned(node.idx)) && !node.ctx.paused.Load() {
+		node.run(params, false)
+		return
+	}
+	node.enqueue(params)
+}
+
+// enqueue 将已标记为运行中的节点提交到协程池，运行暂停时暂存
+func (node *runtimeNode[T]) enqueue(params T) {
+	f := func() { node.run(params, true) }
+	if !node.ctx.park(f) {
+		node.submit(f)
+	}
+}
+
+// submit 提交节点的任务，设置了 NoSteal 的节点不可被工作窃取
+func (node *runtimeNode[T]) submit(f func()) {
+	if node.pinned {
+		node.ctx.submitPinned(f)
 	} else {
-		node.ctx.pool.Submit(func() {
-			node.run(params)
-		})
+		node.ctx.submit(f)
+	}
+}
+
+// markReady 将节点标记为运行中并计入运行中的任务数，返回是否标记成功
+func (node *runtimeNode[T]) markReady() bool {
+	if !node.status.CompareAndSwap(Waiting, Running) {
+		return false
+	}
+	node.ctx.add()
+	node.ready = time.Now()
+	node.ctx.stateChanged(node.name, Waiting, Running)
+	return true
+}
+
+// run 执行节点，cont 为 true（当前协程为协程池 worker）时在当前协程内继续执行被唤醒的第一个子节点，其余被唤醒的子节点正常调度，
+// 使依赖链与汇合节点无需再经过协程池排队
+func (node *runtimeNode[T]) run(params T, cont bool) {
+	node.follow(node.runOnce(params), params, cont)
+}
+
+// follow 在当前协程内依次续跑被唤醒的子节点。协程池有排队中的任务或子节点不可被窃取时不续跑，以免越过更高优先级的任务
+func (node *runtimeNode[T]) follow(next *runtimeNode[T], params T, cont bool) {
+	for next != nil {
+		if !cont || next.pinned || !node.ctx.idle() {
+			next.schedule(params)
+			return
+		}
+		cur := next
+		if node.ctx.park(func() { cur.run(params, true) }) {
+			return
+		}
+		next = cur.runOnce(params)
 	}
 }
 
-func (node *runtimeNode[T]) run(params T) {
-	defer node.ctx.wg.Done()
-	if node.totalTimeout > 0 && time.Now().After(node.ctx.begin.Add(node.totalTimeout)) {
-		node.fail(params, TimeoutErr)
+// runOnce 执行节点并通知子节点，返回需要在当前协程内继续执行的子节点。
+// 节点被推迟执行或推迟重试时返回 nil，由恢复执行的任务结束节点并通知子节点
+func (node *runtimeNode[T]) runOnce(params T) *runtimeNode[T] {
+	if !node.execute(params) {
+		return nil
+	}
+	return node.settle(params)
+}
+
+// execute 执行节点直到进入终态，准入控制推迟执行（见 admit）或 processor 推迟重试（见 deferRetry）时返回 false
+func (node *runtimeNode[T]) execute(params T) bool {
+	node.inheritTimeout()
+	if node.cacheKey != nil && node.reused == nil {
+		node.inputKey = node.cacheKey(params)
+	}
+	if node.reused != nil {
+		node.finish(Succeeded, nil)
+		node.ctx.stateChanged(node.name, Running, Succeeded)
+		if node.ctx.resultSink != nil || node.ctx.eventSink != nil {
+			result := node.getResult()
+			if node.ctx.resultSink != nil {
+				node.ctx.resultSink.OnResult(node.ctx.runID, result)
+			}
+			node.ctx.emitNodeFinished(result)
+		}
+	} else if cause := node.ctx.cause(); cause != nil {
+		node.cancel(params, cause)
+	} else if err := node.upstreamPanic.Load(); err != nil {
+		if node.finish(Skipped, err) {
+			node.callHooks(params)
+		}
+	} else if node.dagRun.disabled != nil && node.dagRun.disabled[node.idx] {
+		node.cancel(params, DisabledErr)
+	} else if node.dagRun.isPruned(node.idx) {
+		node.skip(params)
+	} else if err := node.depCondPanic.Load(); err != nil {
+		node.fail(params, err)
+	} else if node.routedOut.Load() || (node.depSkipped.Load() && node.skipPolicy == SkipPropagate) {
+		node.skip(params)
+	} else if ok, err := node.checkCondition(params); err != nil {
+		node.fail(params, err)
+	} else if !ok {
+		node.skip(params)
+	} else if node.quarantine != nil && node.quarantine.quarantined() {
+		if node.finish(Skipped, QuarantinedErr) {
+			node.callHooks(params)
+		}
+	} else if node.totalTimeout > 0 && time.Now().After(node.ctx.begin.Add(node.totalTimeout)) {
+		node.fail(params, &TimeoutError{NodeName: node.name, Deadline: TotalTimeoutErr, Timeout: node.totalTimeout})
 	} else if node.processor == nil {
 		node.success(params)
+	} else if node.ctx.admission != nil {
+		return node.admit(params)
+	} else {
+		return node.executeAdmitted(params)
+	}
+	return true
+}
+
+// executeAdmitted 执行通过准入控制的节点，processor 推迟重试时返回 false
+func (node *runtimeNode[T]) executeAdmitted(params T) bool {
+	if node.loadMemo() || node.loadCache(params) {
+		node.success(params)
+	} else if !node.acquireSlot() {
+		node.cancel(params, node.ctx.cause())
 	} else if node.localTimeout <= 0 && node.totalTimeout <= 0 {
-		node.processWithoutTimeout(params)
+		return !node.processWithoutTimeout(params)
 	} else {
 		node.processWithTimeout(params)
 	}
-	if node.status.Load() == Succeeded {
-		for _, child := range node.children {
-			child.onDepDone(params)
+	return true
+}
+
+// settle 在节点进入终态后处理隔离、终止运行等后续动作并通知子节点，返回需要在当前协程内继续执行的子节点
+func (node *runtimeNode[T]) settle(params T) (next *runtimeNode[T]) {
+	defer node.ctx.release()
+	// 仅统计实际执行过 processor 的结果
+	if node.quarantine != nil && node.attempts > 0 {
+		node.quarantine.record(node.status.Load() == Succeeded)
+	}
+	if node.status.Load() == Failed && node.abortOnFailure() {
+		node.ctx.abort(&FailFastErr{NodeName: node.name, Err: node.ctx.sanitize(node.err)})
+	}
+	if node.depCnt == 0 && node.ctx.noProgress != nil && node.status.Load() == Failed {
+		if err := node.ctx.noProgress.onRootFailed(node.name, node.ctx.sanitize(node.err)); err != nil {
+			node.ctx.abort(err)
 		}
 	}
-	for _, child := range node.weakChildren {
-		child.onDepDone(params)
+	wake := func(child *runtimeNode[T], weak bool) {
+		if !child.onDepDone(weak) {
+			return
+		}
+		// 锚点在依赖传播时同步结束，不占用当前协程的续跑名额
+		if child.anchor && !node.ctx.paused.Load() && child.markReady() {
+			grandchild := child.runOnce(params)
+			if grandchild == nil {
+				return
+			}
+			if next == nil {
+				next = grandchild
+			} else {
+				grandchild.schedule(params)
+			}
+			return
+		}
+		if next == nil && child.markReady() {
+			next = child
+		} else {
+			child.start(params)
+		}
+	}
+	switch node.status.Load() {
+	case Succeeded:
+		node.forEachChild(node.children, node.dagRun.plan.children[node.idx], func(child *runtimeNode[T]) {
+			if node.routesOut(child) {
+				child.routedOut.Store(true)
+			} else if ok, err := child.checkDepCondition(node.idx, params); err != nil {
+				child.depCondPanic.CompareAndSwap(nil, err)
+			} else if !ok {
+				child.depSkipped.Store(true)
+			}
+			child.onStrongDepDone()
+			wake(child, false)
+		})
+	case Skipped:
+		node.forEachChild(node.children, node.dagRun.plan.children[node.idx], func(child *runtimeNode[T]) {
+			child.depSkipped.Store(true)
+			if err := node.upstreamPanic.Load(); err != nil {
+				child.upstreamPanic.CompareAndSwap(nil, err)
+			}
+			child.onStrongDepDone()
+			wake(child, false)
+		})
+	case Failed:
+		if err := node.branchPanic(); err != nil {
+			node.forEachChild(node.children, node.dagRun.plan.children[node.idx], func(child *runtimeNode[T]) {
+				child.upstreamPanic.CompareAndSwap(nil, err)
+				child.onStrongDepDone()
+				wake(child, false)
+			})
+		}
+	}
+	node.forEachChild(node.weakChildren, node.dagRun.plan.weakChildren[node.idx], func(child *runtimeNode[T]) {
+		// 不等待弱依赖的子节点可能已结束
+		if status := node.status.Load(); status != Succeeded && status != Skipped && !child.status.Load().IsTerminal() {
+			child.degraded.Store(true)
+		}
+		wake(child, true)
+	})
+	return next
+}
+
+// checkDepCondition 对下标为 depIdx 的强依赖求值边条件，发生 panic 时返回 *PanicErr
+func (node *runtimeNode[T]) checkDepCondition(depIdx int, params T) (ok bool, err *PanicErr) {
+	cond := node.depConds[depIdx]
+	if cond == nil {
+		return true, nil
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			err = recovered(node, e)
+		}
+	}()
+	return cond(params), nil
+}
+
+// checkCondition 判断节点是否需要执行，Condition 发生 panic 时返回 *PanicErr
+func (node *runtimeNode[T]) checkCondition(params T) (ok bool, err error) {
+	if node.condition == nil {
+		return true, nil
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			err = recovered(node, e)
+		}
+	}()
+	return node.condition(node, params), nil
+}
+
+// forEachChild 遍历子节点，懒加载模式下按下标获取（必要时创建）运行时节点
+func (node *runtimeNode[T]) forEachChild(children []*runtimeNode[T], indexes []int, f func(child *runtimeNode[T])) {
+	if node.dagRun.lazyNodes == nil {
+		for _, child := range children {
+			f(child)
+		}
+		return
+	}
+	for _, idx := range indexes {
+		f(node.dagRun.node(idx))
+	}
+}
+
+// loadCache 尝试从外部缓存加载结果，返回是否命中
+func (node *runtimeNode[T]) loadCache(params T) bool {
+	if node.load == nil || node.ctx.mock != nil {
+		return false
+	}
+	hit, err := node.load(node, params)
+	node.cacheHit = hit && err == nil
+	return node.cacheHit
+}
+
+// acquireSlot 先获取租约，再依次获取本次运行、节点自身、共享限制器与外部资源的并发执行名额，运行被终止时归还已获取的名额并返回 false。
+// 先获取本次运行独占的名额，避免等待运行级名额时占用在多个运行间共享的名额
+func (node *runtimeNode[T]) acquireSlot() (ok bool) {
+	var acquired []func()
+	defer func() {
+		if !ok {
+			for i := len(acquired) - 1; i >= 0; i-- {
+				acquired[i]()
+			}
+		}
+	}()
+	// 租约可能需要等待其他进程释放，先于共享的名额获取，避免等待期间占用名额阻塞其他节点
+	if node.exclusive && node.ctx.leases != nil {
+		lost := func() { node.terminate(node.dagRun.params, LeaseLostErr) }
+		if node.lease = node.ctx.acquireLease(node.dagRun.dag.Name(), node.name, lost); node.lease == nil {
+			return false
+		}
+		acquired = append(acquired, func() {
+			node.lease.release()
+			node.lease = nil
+		})
+	}
+	if node.ctx.runLimiter != nil {
+		if !node.ctx.runLimiter.Acquire(node.ctx.ctx) {
+			return false
+		}
+		acquired = append(acquired, node.ctx.runLimiter.Release)
+	}
+	if node.slots != nil {
+		select {
+		case node.slots <- struct{}{}:
+			acquired = append(acquired, func() { <-node.slots })
+		case <-node.ctx.ctx.Done():
+			return false
+		}
+	}
+	if node.ctx.limiter != nil {
+		if !node.ctx.limiter.Acquire(node.ctx.ctx) {
+			return false
+		}
+		acquired = append(acquired, node.ctx.limiter.Release)
+	}
+	if node.ctx.resources != nil {
+		if !node.ctx.resources.acquire(node.ctx.ctx, node.resources) {
+			return false
+		}
+		acquired = append(acquired, func() { node.ctx.resources.release(node.resources) })
+	}
+	// 等待名额期间租约丢失时节点已被终止
+	return node.status.Load() == Running
+}
+
+// releaseSlot 在 processor 真正执行完成后按获取的相反顺序归还名额，超时后仍在执行的 processor 也会占用名额
+func (node *runtimeNode[T]) releaseSlot() {
+	if node.ctx.resources != nil {
+		node.ctx.resources.release(node.resources)
+	}
+	if node.ctx.limiter != nil {
+		node.ctx.limiter.Release()
+	}
+	if node.slots != nil {
+		<-node.slots
+	}
+	if node.ctx.runLimiter != nil {
+		node.ctx.runLimiter.Release()
+	}
+	if node.lease != nil {
+		node.lease.release()
 	}
 }
 
 func (node *runtimeNode[T]) process(params T) (err error) {
 	defer func() {
 		if e := recover(); e != nil {
-			err = fmt.Errorf("recover panic over node %s: %v", node.name, e)
+			err = recovered(node, e)
 		}
 	}()
-	return node.processor(node, params)
+	if node.ctx.mock != nil {
+		return node.mockProcess()
+	}
+	processor := node.processor
+	if node.canary != nil && node.ctx.canary {
+		processor = node.canary
+	}
+	return processor(node, params)
 }
 
-func (node *runtimeNode[T]) processWithRetry(params T) {
-	var err error
+// variant 返回节点本次执行的实现，没有金丝雀实现或未执行时返回空字符串
+func (node *runtimeNode[T]) variant() string {
+	if node.canary == nil || node.attempts == 0 {
+		return ""
+	}
+	if node.ctx.canary {
+		return VariantCanary
+	}
+	return VariantBaseline
+}
+
+// processWithRetry 执行 processor 直到成功或重试耗尽，返回是否推迟了重试
+func (node *runtimeNode[T]) processWithRetry(params T) (deferred bool) {
+	return node.attempt(params, nil)
+}
+
+// attempt 执行剩余的重试，err 为上一次执行的错误。协程池过载需要推迟重试时归还名额并返回 true，节点由 resumeRetry 继续执行
+func (node *runtimeNode[T]) attempt(params T, err error) (deferred bool) {
+	// cancelled 运行被取消导致重试中断，此时 err 为取消原因
+	var cancelled bool
 	defer func() {
-		node.cost.Store(int64(time.Since(node.begin)))
-		close(node.done)
-		if err == nil {
-			node.success(params)
-		} else {
-			node.fail(params, err)
+		if deferred {
+			return
 		}
+		node.ctx.gauge.dec()
+		node.releaseSlot()
+		node.endProcess(params, err, cancelled)
 	}()
 	maxAttempts := maxUint(1, node.maxAttempts)
 	for node.attempts < maxAttempts {
+		retry := node.attempts > 0
+		if retry {
+			if cause := node.ctx.cause(); cause != nil {
+				err, cancelled = cause, true
+				return
+			}
+			if node.poolBusy() {
+				if node.retryQueueFail || node.status.Load() != Running {
+					return
+				}
+				node.deferRetry(params, err)
+				return true
+			}
+			if node.ctx.breaker != nil && !node.ctx.breaker.acquire(node.ctx.ctx) {
+				err, cancelled = node.ctx.cause(), true
+				return
+			}
+		}
 		ok := node.DoIfRunning(func() {
 			node.attempts++
 		})
 		// 避免超时后继续重跑
 		if !ok {
+			if retry && node.ctx.breaker != nil {
+				node.ctx.breaker.release()
+			}
 			return
 		}
+		if retry {
+			node.ctx.retrying.inc()
+		}
+		if node.onStart != nil {
+			node.onStart(node, params)
+		}
 		err = node.process(params)
+		if retry {
+			node.ctx.retrying.dec()
+			if node.ctx.breaker != nil {
+				node.ctx.breaker.release()
+			}
+		}
 		if err == nil {
 			return
 		}
+		if _, panicked := err.(*PanicErr); panicked && !node.retryOnPanic {
+			return
+		}
+		if node.retryIf != nil && !node.retryIf(err) {
+			return
+		}
+		if node.attempts != maxAttempts && node.onRetry != nil {
+			node.onRetry(node, params, node.attempts, err)
+		}
 		if node.attempts != maxAttempts && node.backoffFunc != nil {
 			// 避免超时后无效等待
 			if node.status.Load() != Running {
 				return
 			}
-			time.Sleep(node.backoffFunc(node.attempts))
+			if !node.sleep(node.backoffFunc(node.attempts)) {
+				err, cancelled = node.ctx.cause(), true
+				return
+			}
 		}
 	}
 	return
 }
 
-func (node *runtimeNode[T]) processWithoutTimeout(params T) {
+// endProcess 在 processor 不再执行后保存结果并结束节点，err 为最后一次执行的错误，cancelled 为 true 时 err 为运行被取消的原因
+func (node *runtimeNode[T]) endProcess(params T, err error, cancelled bool) {
+	// 超时后不再保存结果
+	if err == nil && node.status.Load() == Running {
+		if node.persist != nil && node.ctx.mock == nil {
+			node.persistErr = node.persist(node, params)
+		}
+		node.storeMemo()
+	}
+	node.cost.Store(int64(time.Since(node.begin)))
+	// processor 感知到截止时间后返回时，可能先于超时检测结束，此时同样视为超时
+	if node.execCtx != nil && context.Cause(node.execCtx) == TimeoutErr {
+		node.timeout(params)
+	}
+	close(node.done)
+	if err == nil {
+		node.success(params)
+	} else if cancelled {
+		node.cancel(params, err)
+	} else {
+		node.fail(params, err)
+	}
+	if node.execCancel != nil {
+		node.execCancel(nil)
+	}
+	close(node.settled)
+}
+
+// retryDeferInterval 协程池过载时推迟重试的检查间隔
+const retryDeferInterval = 10 * time.Millisecond
+
+// poolBusy 协程池排队任务数是否超过 retryQueueLimit
+func (node *runtimeNode[T]) poolBusy() bool {
+	if node.retryQueueLimit <= 0 {
+		return false
+	}
+	pool, ok := node.ctx.pool.(IQueuedPool)
+	return ok && pool.QueueLen() > node.retryQueueLimit
+}
+
+// deferRetry 归还名额并推迟重试，不占用当前 worker。排队任务数下降、节点超时或运行被取消后，以新的任务调用 resumeRetry
+func (node *runtimeNode[T]) deferRetry(params T, err error) {
+	node.ctx.gauge.dec()
+	node.releaseSlot()
+	var check func()
+	check = func() {
+		if node.ctx.cause() == nil && node.status.Load() == Running && node.poolBusy() {
+			time.AfterFunc(retryDeferInterval, check)
+			return
+		}
+		node.submit(func() { node.resumeRetry(params, err) })
+	}
+	time.AfterFunc(retryDeferInterval, check)
+}
+
+// resumeRetry 重新获取名额后继续推迟的重试，节点结束后由当前协程通知子节点（设置了超时时由 processWithTimeout 通知）
+func (node *runtimeNode[T]) resumeRetry(params T, err error) {
+	if node.status.Load() != Running {
+		// 已超时或被终止
+		node.endProcess(params, err, false)
+	} else if node.ctx.cause() != nil || !node.acquireSlot() {
+		node.endProcess(params, node.ctx.cause(), true)
+	} else {
+		node.ctx.gauge.inc()
+		if node.attempt(params, err) {
+			return
+		}
+	}
+	if !node.awaited {
+		node.follow(node.settle(params), params, true)
+	}
+}
+
+// onStrongDepDone 记录一个强依赖完成的时间，保留最晚的一个
+func (node *runtimeNode[T]) onStrongDepDone() {
+	if !node.inherit {
+		return
+	}
+	now := time.Now().UnixNano()
+	for {
+		last := node.strongDone.Load()
+		if last >= now || node.strongDone.CompareAndSwap(last, now) {
+			return
+		}
+	}
+}
+
+// inheritTimeout 按最慢的强依赖完成时的剩余运行时间与最长强依赖链长度分摊本地超时时间
+func (node *runtimeNode[T]) inheritTimeout() {
+	if !node.inherit || node.localTimeout > 0 {
+		return
+	}
+	ddl, ok := node.ctx.ctx.Deadline()
+	if !ok {
+		return
+	}
+	from := node.ctx.begin
+	if last := node.strongDone.Load(); last != 0 {
+		from = time.Unix(0, last)
+	}
+	// 剩余时间不足时至少保留 1ns，使节点立即超时，而不是被视为无超时
+	node.localTimeout = ddl.Sub(from) / time.Duration(node.chainLen)
+	if node.localTimeout <= 0 {
+		node.localTimeout = 1
+	}
+}
+
+// sleep 退避等待，运行被取消时提前返回 false
+func (node *runtimeNode[T]) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-node.ctx.ctx.Done():
+		return false
+	}
+}
+
+// processWithoutTimeout 在当前协程内执行 processor，返回是否推迟了重试
+func (node *runtimeNode[T]) processWithoutTimeout(params T) (deferred bool) {
 	node.begin = time.Now()
-	node.processWithRetry(params)
+	// 未设置超时同样使用独立的上下文，使 Kill 与租约丢失能够取消执行中的 processor，上下文在 endProcess 中释放
+	execCtx, cancelCause := context.WithCancelCause(node.ctx.ctx)
+	node.mu.Lock()
+	node.execCtx = execCtx
+	node.execCancel = cancelCause
+	node.mu.Unlock()
+	node.ctx.gauge.inc()
+	node.emitStarted()
+	return node.processWithRetry(params)
+}
+
+// emitStarted 发送 node.started 事件
+func (node *runtimeNode[T]) emitStarted() {
+	if event := node.ctx.newEvent(EventNodeStarted); event != nil {
+		event.Time = node.begin
+		event.Node = node.name
+		node.ctx.eventSink.OnEvent(event)
+	}
 }
 
 func (node *runtimeNode[T]) processWithTimeout(params T) {
@@ -182,64 +843,200 @@ func (node *runtimeNode[T]) processWithTimeout(params T) {
 	process := func() {
 		node.begin = time.Now()
 		timeout := time.Duration(math.MaxInt64)
+		node.ddlBy = TotalTimeoutErr
 		if node.localTimeout > 0 {
 			timeout = minDuration(timeout, node.localTimeout)
+			node.ddlBy = LocalTimeoutErr
 		}
 		if node.totalTimeout > 0 {
-			timeout = minDuration(timeout, node.ctx.begin.Add(node.totalTimeout).Sub(node.begin))
+			if remaining := node.ctx.begin.Add(node.totalTimeout).Sub(node.begin); remaining < timeout {
+				timeout = remaining
+				node.ddlBy = TotalTimeoutErr
+			}
 		}
 		node.ddl = node.begin.Add(timeout)
+		// 超时检测可能先于上下文自身的定时器触发，此时通过 execCancel 主动结束上下文，保证 context.Cause 一致。
+		// 上下文在 endProcess 中释放，推迟重试期间保持有效
+		execCtx, cancelCause := context.WithCancelCause(node.ctx.ctx)
+		execCtx, stop := context.WithDeadlineCause(execCtx, node.ddl, TimeoutErr)
+		node.mu.Lock()
+		node.execCtx = execCtx
+		node.execCancel = func(cause error) {
+			cancelCause(cause)
+			stop()
+		}
+		node.mu.Unlock()
+		node.ctx.gauge.inc()
+		node.emitStarted()
 		close(started)
 		node.processWithRetry(params)
 	}
-	if node.ctx.pool == nil {
-		go process()
-	} else {
-		node.ctx.pool.Submit(process)
-	}
+	node.awaited = true
+	node.submit(process)
 	<-started
 	select {
-	case <-node.done:
-		break
+	case <-node.finished:
 	case <-time.After(time.Until(node.ddl)):
-		// 在超时时，可能processor正在调用DoIfRunning，需要加锁，其余情况无并发冲突，无需加锁
-		node.mu.Lock()
-		node.fail(params, TimeoutErr)
-		node.mu.Unlock()
+		node.timeout(params)
+	}
+	// processor 已返回时等待其结束节点并调用完钩子函数后再通知子节点，被 Kill 终止或超时时不再等待仍在执行的 processor
+	select {
+	case <-node.done:
+		<-node.settled
+	default:
 	}
 }
 
-func (node *runtimeNode[T]) onDepDone(params T) {
-	if node.doneDepCnt.Add(1) == node.depCnt {
-		node.start(params)
+// timeout 将节点标记为超时失败，可能被重复调用
+func (node *runtimeNode[T]) timeout(params T) {
+	// 在超时时，可能processor正在调用DoIfRunning，需要加锁，其余情况无并发冲突，无需加锁
+	// 钩子函数在释放锁之后再调用，避免钩子内调用 GetCost 等方法时死锁
+	node.execCancel(TimeoutErr)
+	// 运行的整体超时时间先于节点的截止时间到期时，视为全局超时
+	deadline := node.ddlBy
+	if time.Now().Before(node.ddl) {
+		deadline = TotalTimeoutErr
+	}
+	node.mu.Lock()
+	failed := node.finish(Failed, &TimeoutError{
+		NodeName: node.name,
+		Deadline: deadline,
+		Timeout:  node.ddl.Sub(node.begin),
+		Elapsed:  time.Since(node.begin),
+		Attempt:  node.attempts,
+	})
+	node.mu.Unlock()
+	if failed {
+		node.callHooks(params)
 	}
 }
 
-func (node *runtimeNode[T]) success(params T) {
-	if !node.status.CompareAndSwap(Running, Succeeded) {
-		return
+// kill 以 OperatorKilledErr 终止运行中的节点，返回是否终止成功
+func (node *runtimeNode[T]) kill(params T) bool {
+	return node.terminate(params, OperatorKilledErr)
+}
+
+// terminate 以 err 将运行中的节点标记为失败并取消其执行上下文，返回是否终止成功
+func (node *runtimeNode[T]) terminate(params T, err error) bool {
+	node.mu.Lock()
+	terminated := node.finish(Failed, err)
+	execCancel := node.execCancel
+	node.mu.Unlock()
+	if !terminated {
+		return false
 	}
-	if node.onSuccess != nil {
-		node.onSuccess(node, params)
+	if execCancel != nil {
+		execCancel(err)
+	}
+	node.callHooks(params)
+	return true
+}
+
+// onDepDone 记录一个依赖已完成，weak 为 true 时为弱依赖，返回节点是否就绪：所有依赖均已完成，
+// 设置了 EarlyStart 时为所有强依赖均已完成（没有强依赖的节点与根节点一同调度，不在此就绪）
+func (node *runtimeNode[T]) onDepDone(weak bool) bool {
+	if node.depCnt > 1 {
+		node.firstDepDone.CompareAndSwap(0, time.Now().UnixNano())
+	}
+	allDone := node.doneDepCnt.Add(1) == node.depCnt
+	if !node.earlyStart {
+		return allDone
+	}
+	return !weak && node.doneStrong.Add(1) == node.strongCnt
+}
+
+func (node *runtimeNode[T]) success(params T) {
+	if node.finish(Succeeded, nil) {
+		node.callHooks(params)
 	}
 }
 
 func (node *runtimeNode[T]) fail(params T, err error) {
-	if !node.status.CompareAndSwap(Running, Failed) {
-		return
+	if node.finish(Failed, err) {
+		node.callHooks(params)
+	}
+}
+
+func (node *runtimeNode[T]) skip(params T) {
+	if node.finish(Skipped, nil) {
+		node.callHooks(params)
+	}
+}
+
+func (node *runtimeNode[T]) cancel(params T, cause error) {
+	if node.finish(Cancelled, cause) {
+		node.callHooks(params)
+	}
+}
+
+// finish 将节点从运行中切换为终态，返回是否切换成功
+func (node *runtimeNode[T]) finish(status NodeStatus, err error) bool {
+	if !node.status.CompareAndSwap(Running, status) {
+		return false
 	}
 	node.err = err
-	if node.onFailure != nil {
+	close(node.finished)
+	return true
+}
+
+func (node *runtimeNode[T]) callHooks(params T) {
+	node.ctx.stateChanged(node.name, Running, node.status.Load())
+	if node.status.Load() == Succeeded {
+		if node.onSuccess != nil {
+			node.onSuccess(node, params)
+		}
+	} else if node.status.Load() == Failed && node.onFailure != nil {
 		node.onFailure(node, params)
 	}
+	if node.onFinish == nil && node.ctx.resultSink == nil && node.ctx.eventSink == nil {
+		return
+	}
+	result := node.getResult()
+	if node.onFinish != nil {
+		node.onFinish(node, params, result)
+	}
+	if node.ctx.resultSink != nil {
+		node.ctx.resultSink.OnResult(node.ctx.runID, result)
+	}
+	node.ctx.emitNodeFinished(result)
 }
 
 func (node *runtimeNode[T]) getResult() *NodeResult {
-	return &NodeResult{
-		Status:   int(node.status.Load()),
-		Err:      node.err,
-		Begin:    node.begin,
-		Cost:     node.GetCost(),
-		Attempts: node.attempts,
+	if node.reused != nil {
+		result := *node.reused
+		result.Reused = true
+		return &result
+	}
+	var panicErr *PanicErr
+	result := &NodeResult{
+		Name:         node.name,
+		Meta:         node.meta,
+		Tags:         node.tags,
+		Owner:        node.owner,
+		AlertChannel: node.alertChannel,
+		Status:       node.status.Load(),
+		Err:          node.ctx.sanitize(node.err),
+		Output:       node.output,
+		Begin:        node.begin,
+		Attempts:     node.attempts,
+		Panicked:     errors.As(node.err, &panicErr),
+		TimeoutBy:    timeoutBy(node.err),
+		Degraded:     node.degraded.Load(),
+		CacheHit:     node.cacheHit,
+		InputKey:     node.inputKey,
+		Variant:      node.variant(),
+		PersistErr:   node.persistErr,
+	}
+	node.subRunsMu.Lock()
+	result.SubRuns = slices.Clone(node.subRuns)
+	node.subRunsMu.Unlock()
+	if first := node.firstDepDone.Load(); first != 0 && !node.ready.IsZero() {
+		result.FanInWait = node.ready.Sub(time.Unix(0, first))
+	}
+	// 未开始执行的节点没有耗时
+	if !node.begin.IsZero() {
+		result.Cost = node.GetCost()
+		result.QueueWait = node.begin.Sub(node.ready)
 	}
+	return result
 }
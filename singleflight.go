@@ -0,0 +1,56 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"sync"
+)
+
+// flightGroup 合并键相同的并发运行，同一时刻每个键只有一次运行在执行
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done   chan struct{}
+	result *RunResult
+}
+
+// do 执行 run 或等待键相同的进行中运行结束，返回运行结果与结果是否来自其他调用方的运行
+func (g *flightGroup) do(key string, run func() *RunResult) (*RunResult, bool) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		return call.result, true
+	}
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.result = run()
+	return call.result, false
+}
+
+// RunShared 与 Run 相同，但与键相同（见 BuildOptions.DedupKey）的进行中运行合并为一次执行，返回共享的运行结果与结果是否来自其他调用方的运行。
+// 合并时只有发起运行的调用方的 params 会被节点写入，运行结果被所有调用方共享，不可修改。未设置键函数时不合并
+func (dag *DAG[T]) RunShared(params T) (*RunResult, bool) {
+	if dag.dedupKey == nil {
+		return dag.Run(params), false
+	}
+	return dag.flights.do(dag.dedupKey(params), func() *RunResult {
+		return dag.Run(params)
+	})
+}
@@ -0,0 +1,88 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// NodeSLA 节点的服务等级目标，零值字段表示不设目标
+type NodeSLA struct {
+	MaxCost        time.Duration `json:"max_cost,omitempty"`         // 单次运行的最大耗时
+	MaxFailureRate float64       `json:"max_failure_rate,omitempty"` // 图构建以来实际执行的运行中失败的最大比例，取值 (0, 1]
+}
+
+// SLABreachKind SLA 违约的类型
+type SLABreachKind string
+
+const (
+	BreachRunDuration     SLABreachKind = "run_duration"      // 图运行总耗时超过目标
+	BreachNodeCost        SLABreachKind = "node_cost"         // 节点耗时超过目标
+	BreachNodeFailureRate SLABreachKind = "node_failure_rate" // 节点失败率超过目标
+)
+
+// SLABreach 一次 SLA 违约
+type SLABreach struct {
+	Kind   SLABreachKind `json:"kind"`
+	Node   string        `json:"node,omitempty"` // 违约的节点名称，图级别的违约为空
+	Target float64       `json:"target"`         // 目标值，耗时以纳秒表示
+	Actual float64       `json:"actual"`         // 实际值，耗时以纳秒表示
+}
+
+func (b SLABreach) String() string {
+	switch b.Kind {
+	case BreachRunDuration:
+		return fmt.Sprintf("%s: %v > %v", b.Kind, time.Duration(b.Actual), time.Duration(b.Target))
+	case BreachNodeCost:
+		return fmt.Sprintf("%s %s: %v > %v", b.Kind, b.Node, time.Duration(b.Actual), time.Duration(b.Target))
+	default:
+		return fmt.Sprintf("%s %s: %.4f > %.4f", b.Kind, b.Node, b.Actual, b.Target)
+	}
+}
+
+// slaStats 节点在所有运行间的执行统计，用于计算失败率
+type slaStats struct {
+	total  atomic.Int64
+	failed atomic.Int64
+}
+
+// checkSLA 记录各节点的执行统计并检查 SLA 违约，每次运行只在结束时调用一次
+func (run *dagRun[T]) checkSLA(result *RunResult) []SLABreach {
+	var breaches []SLABreach
+	if target := run.dag.slaMaxDuration; target > 0 && result.Cost > target {
+		breaches = append(breaches, SLABreach{Kind: BreachRunDuration, Target: float64(target), Actual: float64(result.Cost)})
+	}
+	for i, node := range run.dag.metaNodes {
+		r := result.Results[i]
+		// 复用历史运行或命中缓存的节点未被执行，其耗时不反映节点的性能
+		if node.sla.MaxCost > 0 && !r.Reused && !r.CacheHit && r.Cost > node.sla.MaxCost {
+			breaches = append(breaches, SLABreach{Kind: BreachNodeCost, Node: node.name, Target: float64(node.sla.MaxCost), Actual: float64(r.Cost)})
+		}
+		if node.sla.MaxFailureRate <= 0 || r.Reused || (r.Status != Succeeded && r.Status != Failed) {
+			continue
+		}
+		total := node.slaStats.total.Add(1)
+		failed := node.slaStats.failed.Load()
+		if r.Status == Failed {
+			failed = node.slaStats.failed.Add(1)
+		}
+		if rate := float64(failed) / float64(total); rate > node.sla.MaxFailureRate {
+			breaches = append(breaches, SLABreach{Kind: BreachNodeFailureRate, Node: node.name, Target: node.sla.MaxFailureRate, Actual: rate})
+		}
+	}
+	return breaches
+}
+
+// slaDefinition 返回节点 SLA 的副本，未设置时返回 nil
+func (metaData *nodeMetadata[T]) slaDefinition() *NodeSLA {
+	if metaData.sla == (NodeSLA{}) {
+		return nil
+	}
+	sla := metaData.sla
+	return &sla
+}
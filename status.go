@@ -5,9 +5,70 @@
 
 package easydag
 
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// NodeStatus 节点运行状态
+type NodeStatus int32
+
 const (
-	Waiting = iota
-	Running
-	Succeeded
-	Failed
+	Waiting   NodeStatus = iota // 等待依赖完成或等待调度
+	Running                     // 正在运行
+	Succeeded                   // 运行成功
+	Failed                      // 运行失败
+	Cancelled                   // 运行被取消，节点未执行或在重试前被中断
+	Skipped                     // 节点的 Condition 返回 false 或强依赖被跳过，节点未执行
 )
+
+var statusNames = [...]string{"waiting", "running", "succeeded", "failed", "cancelled", "skipped"}
+
+func (s NodeStatus) String() string {
+	if s < 0 || int(s) >= len(statusNames) {
+		return "unknown"
+	}
+	return statusNames[s]
+}
+
+func (s NodeStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *NodeStatus) UnmarshalText(text []byte) error {
+	for i, name := range statusNames {
+		if name == string(text) {
+			*s = NodeStatus(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown node status %q", text)
+}
+
+// IsTerminal 是否为终态，终态的节点不会再发生状态变化
+func (s NodeStatus) IsTerminal() bool {
+	return s == Succeeded || s == Failed || s == Cancelled || s == Skipped
+}
+
+// SkipPolicy 强依赖被跳过时下游节点的处理策略
+type SkipPolicy int
+
+const (
+	// SkipPropagate 下游节点同样被跳过
+	SkipPropagate SkipPolicy = iota
+	// SkipAsSatisfied 将被跳过的依赖视为已满足，下游节点正常执行
+	SkipAsSatisfied
+)
+
+// atomicStatus 可并发读写的节点状态
+type atomicStatus struct {
+	v atomic.Int32
+}
+
+func (s *atomicStatus) Load() NodeStatus {
+	return NodeStatus(s.v.Load())
+}
+
+func (s *atomicStatus) CompareAndSwap(old, new NodeStatus) bool {
+	return s.v.CompareAndSwap(int32(old), int32(new))
+}
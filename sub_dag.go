@@ -0,0 +1,69 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"iter"
+)
+
+// NewSubDAGNode 将整个图封装为父图中的一个节点，子图以相同的 params 运行，任一子节点失败时该节点失败。
+// 子图继承父运行的 Mock、EventSink、ErrSanitizer 与 FailFast 配置，运行结果挂载在该节点的 NodeResult.SubRuns 中，
+// 可通过 RunResult.Flatten 以带前缀的名称遍历，或通过 RunResult.ByName 以 "父节点名称/子节点名称" 获取
+func NewSubDAGNode[T any](name string, sub *DAG[T]) *Node[T] {
+	return &Node[T]{
+		Name: name,
+		Processor: func(node IRuntimeNode, params T) error {
+			// 子图不复用父图的协程池，避免父节点占用 worker 等待子图时耗尽协程池
+			return sub.RunWithOptions(params, RunOptions{Context: node.Context(), Parent: node}).Err()
+		},
+	}
+}
+
+// inherit 子图运行继承父运行的模拟运行、事件、脱敏与快速失败配置，子图运行配置中已设置的字段优先。
+// 继承 EventSink 且未设置 SampleRate 时沿用父运行的采样结果，使同一次运行的事件完整
+func (ctx *dagCtx) inherit(parent *dagCtx, opts RunOptions) {
+	if ctx.mock == nil {
+		ctx.mock = parent.mock
+	}
+	if ctx.sanitizer == nil {
+		ctx.sanitizer = parent.sanitizer
+	}
+	if ctx.eventSink == nil {
+		ctx.eventSink = parent.eventSink
+		if opts.SampleRate == 0 {
+			ctx.sampled = parent.sampled
+			ctx.gauge.noSamples = !ctx.sampled
+		}
+	}
+	ctx.failFast = ctx.failFast || parent.failFast
+}
+
+// Flatten 遍历本次运行及其所有子图运行的节点结果，子图节点的名称以 "父节点名称/" 为前缀，
+// 子图节点的结果为副本，修改不会影响原始结果
+func (result *RunResult) Flatten() iter.Seq[*NodeResult] {
+	return func(yield func(*NodeResult) bool) {
+		result.flatten("", yield)
+	}
+}
+
+func (result *RunResult) flatten(prefix string, yield func(*NodeResult) bool) bool {
+	for _, r := range result.Results {
+		if prefix != "" {
+			copied := *r
+			copied.Name = prefix + r.Name
+			r = &copied
+		}
+		if !yield(r) {
+			return false
+		}
+		for _, sub := range r.SubRuns {
+			if !sub.flatten(r.Name+"/", yield) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -6,6 +6,10 @@
 package easydag
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,3 +26,14 @@ func minDuration(a, b time.Duration) time.Duration {
 	}
 	return b
 }
+
+var runSeq atomic.Uint64
+
+// newRunID 生成运行 ID，随机数生成失败时退化为进程内自增序号
+func newRunID() string {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return strconv.FormatUint(runSeq.Add(1), 10)
+	}
+	return hex.EncodeToString(buf[:])
+}
@@ -0,0 +1,247 @@
+// Copyright © 2025 tjj
+//
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package easydag
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"slices"
+	"strings"
+)
+
+// IValidationErr 图校验错误，可通过 NodeNames 获取涉及的节点名称，便于工具或界面定位问题节点
+type IValidationErr interface {
+	error
+	NodeNames() []string
+}
+
+// CycleErr 环形依赖，Path 为环路径，首尾为同一节点
+type CycleErr struct {
+	Path []string
+}
+
+func (e *CycleErr) Error() string {
+	return "cyclic dependency detected: " + strings.Join(e.Path, " -> ")
+}
+
+func (e *CycleErr) NodeNames() []string {
+	return e.Path[:len(e.Path)-1]
+}
+
+// DuplicateNameErr 多个节点使用了相同的名称
+type DuplicateNameErr struct {
+	Name  string
+	Count int
+}
+
+func (e *DuplicateNameErr) Error() string {
+	return fmt.Sprintf("duplicate node name: %s (%d nodes)", e.Name, e.Count)
+}
+
+func (e *DuplicateNameErr) NodeNames() []string {
+	return []string{e.Name}
+}
+
+// DriftErr 节点在构建图之后被修改。图在构建时复制了节点配置，之后的修改不会生效
+type DriftErr struct {
+	Nodes []string // 被修改的节点名称
+}
+
+func (e *DriftErr) Error() string {
+	return "node definition changed after build: " + strings.Join(e.Nodes, ", ")
+}
+
+func (e *DriftErr) NodeNames() []string {
+	return e.Nodes
+}
+
+// MissingConfigErr 节点缺少 RequiredConfig 中的配置项
+type MissingConfigErr struct {
+	NodeName string
+	Keys     []string // 缺失的配置项
+}
+
+func (e *MissingConfigErr) Error() string {
+	return fmt.Sprintf("node %s missing required config: %s", e.NodeName, strings.Join(e.Keys, ", "))
+}
+
+func (e *MissingConfigErr) NodeNames() []string {
+	return []string{e.NodeName}
+}
+
+// ValidationErr 汇总多个校验错误，可通过 errors.As 获取具体的错误类型
+type ValidationErr struct {
+	Errs []IValidationErr
+}
+
+func (e *ValidationErr) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationErr) NodeNames() []string {
+	var names []string
+	for _, err := range e.Errs {
+		names = append(names, err.NodeNames()...)
+	}
+	return names
+}
+
+func (e *ValidationErr) Unwrap() []error {
+	errs := make([]error, len(e.Errs))
+	for i, err := range e.Errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Validate 校验图定义，返回汇总所有问题的 *ValidationErr，包括：
+// 1.用户节点在构建图之后被修改（如再次调用 AddDependency、修改超时时间等）
+// 2.多个节点使用了相同的名称
+// 3.节点缺少 RequiredConfig 中的配置项
+// 构建时已拒绝环形依赖，图内每个节点都可从某个根节点到达，与其它节点都不连通的独立根节点是合法的，不会被报告
+func (dag *DAG[T]) Validate() error {
+	var errs []IValidationErr
+	var drifted []string
+	for idx, node := range dag.nodes {
+		if checksum(node, dag.index) != dag.metaNodes[idx].checksum {
+			drifted = append(drifted, dag.metaNodes[idx].name)
+		}
+	}
+	if len(drifted) > 0 {
+		errs = append(errs, &DriftErr{Nodes: drifted})
+	}
+	nameCnt := make(map[string]int, len(dag.metaNodes))
+	for _, node := range dag.metaNodes {
+		nameCnt[node.name]++
+	}
+	for _, node := range dag.metaNodes {
+		if cnt := nameCnt[node.name]; cnt > 1 {
+			errs = append(errs, &DuplicateNameErr{Name: node.name, Count: cnt})
+			// 同名节点只报告一次
+			nameCnt[node.name] = 0
+		}
+	}
+	for _, node := range dag.metaNodes {
+		var missing []string
+		for _, key := range node.required {
+			if _, ok := node.config[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			errs = append(errs, &MissingConfigErr{NodeName: node.name, Keys: missing})
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationErr{Errs: errs}
+	}
+	return nil
+}
+
+// checksum 计算用户节点中可比较配置的校验和，依赖以图内下标表示，不在图内的依赖记为 -1
+func checksum[T any](node *Node[T], index map[*Node[T]]int) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	writeInt := func(v int64) {
+		binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		_, _ = h.Write(buf[:])
+	}
+	writeDeps := func(deps []*Node[T]) {
+		writeInt(int64(len(deps)))
+		for _, dep := range deps {
+			if dep == nil {
+				continue
+			}
+			if idx, ok := index[dep]; ok {
+				writeInt(int64(idx))
+			} else {
+				writeInt(-1)
+			}
+		}
+	}
+	_, _ = h.Write([]byte(node.Name))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(node.Policy))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(node.Owner))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(node.AlertChannel))
+	_, _ = h.Write([]byte{0})
+	writeMap := func(m map[string]string) {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		writeInt(int64(len(keys)))
+		for _, key := range keys {
+			_, _ = h.Write([]byte(key))
+			_, _ = h.Write([]byte{0})
+			_, _ = h.Write([]byte(m[key]))
+			_, _ = h.Write([]byte{0})
+		}
+	}
+	writeMap(node.Config)
+	meta := make(map[string]string, len(node.Meta))
+	for key, value := range node.Meta {
+		meta[key] = fmt.Sprint(value)
+	}
+	writeMap(meta)
+	writeInt(int64(node.LocalTimeout))
+	writeInt(int64(node.TotalTimeout))
+	writeInt(int64(math.Float64bits(node.Weight)))
+	writeInt(int64(node.Priority))
+	writeInt(int64(node.MaxConcurrent))
+	writeInt(int64(node.SkipPolicy))
+	writeInt(int64(node.SLA.MaxCost))
+	writeInt(int64(math.Float64bits(node.SLA.MaxFailureRate)))
+	writeInt(int64(len(node.Tags)))
+	for _, tag := range node.Tags {
+		_, _ = h.Write([]byte(tag))
+		_, _ = h.Write([]byte{0})
+	}
+	writeInt(int64(len(node.RequiredConfig)))
+	for _, key := range node.RequiredConfig {
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte{0})
+	}
+	writeInt(int64(len(node.Resources)))
+	for _, resource := range node.Resources {
+		_, _ = h.Write([]byte(resource))
+		_, _ = h.Write([]byte{0})
+	}
+	writeInt(int64(node.MaxAttempts))
+	writeInt(int64(node.RetryQueueLimit))
+	writeInt(int64(node.QuarantineAfter))
+	writeInt(int64(node.QuarantineCooldown))
+	writeInt(int64(node.PolicyOverrides))
+	writeBool := func(v bool) {
+		if v {
+			writeInt(1)
+		} else {
+			writeInt(0)
+		}
+	}
+	writeBool(node.RetryOnPanic)
+	writeBool(node.InheritTimeout)
+	writeBool(node.Inline)
+	writeBool(node.RetryQueueFail)
+	writeBool(node.Exclusive)
+	writeBool(node.NoSteal)
+	writeBool(node.EarlyStart)
+	writeDeps(node.Dependencies)
+	for _, dep := range node.Dependencies {
+		writeBool(node.DependencyConditions[dep] != nil)
+	}
+	writeDeps(node.WeakDependencies)
+	return h.Sum64()
+}